@@ -0,0 +1,346 @@
+package gocchan
+
+import (
+	"bytes"
+	"container/list"
+	"encoding/json"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Assignment represents the decision of feature for a subject.
+type Assignment struct {
+	// Whether the feature is active for the subject.
+	Active bool `json:"active"`
+
+	// name of variant which the subject is assigned to if the feature is an experiment.
+	Variant string `json:"variant,omitempty"`
+}
+
+// AssignmentStore is an interface of store of the assignments.
+type AssignmentStore interface {
+	// Get returns the assignment of feature for subject.
+	// If it hasn't been stored, it returns false.
+	Get(featureName, subject string) (Assignment, bool)
+
+	// Set stores the assignment of feature for subject.
+	Set(featureName, subject string, assignment Assignment) error
+
+	// Delete deletes the assignment of feature for subject.
+	Delete(featureName, subject string) error
+}
+
+// global assignment store.
+var assignments AssignmentStore
+
+// SetAssignmentStore sets the global assignment store.
+// The first decisions of ActiveIf and Invoke for each subject are remembered by store,
+// so they stay sticky even if the rules of feature changed, until ResetAssignment is called.
+// The subject is identified by context. See Subject.
+// If store is nil, decisions aren't remembered.
+func SetAssignmentStore(store AssignmentStore) {
+	assignments = store
+}
+
+// ResetAssignment resets the assignment of feature for the subject identified by context.
+func ResetAssignment(featureName string, context interface{}) error {
+	key, ok := subjectKey(context)
+	if !ok || assignments == nil {
+		return nil
+	}
+	return assignments.Delete(featureName, key)
+}
+
+// loadAssignment returns the assignment of feature for subject from the global assignment store.
+func loadAssignment(featureName, subject string) (Assignment, bool) {
+	if assignments == nil {
+		return Assignment{}, false
+	}
+	return assignments.Get(featureName, subject)
+}
+
+// storeAssignment stores the assignment of feature for subject to the global assignment store.
+// The error of store is logged by the standard logger.
+func storeAssignment(featureName, subject string, a Assignment) {
+	if err := assignments.Set(featureName, subject, a); err != nil {
+		log.Printf("gocchan: failed to store assignment of feature `%s`: %v", featureName, err)
+	}
+}
+
+// assignmentKey represents a key of assignment.
+type assignmentKey struct {
+	featureName string
+	subject     string
+}
+
+// assignmentEntry represents an entry of assignment in the memory store.
+type assignmentEntry struct {
+	key        assignmentKey
+	assignment Assignment
+}
+
+// memoryAssignmentStore is an AssignmentStore which stores assignments in memory with LRU eviction.
+type memoryAssignmentStore struct {
+	size    int
+	entries map[assignmentKey]*list.Element
+	lru     *list.List
+	mu      sync.Mutex
+}
+
+// NewMemoryAssignmentStore returns a new AssignmentStore which stores at most size assignments in memory.
+// When it is full, the least recently used assignment is evicted.
+// If size isn't positive, it panic.
+func NewMemoryAssignmentStore(size int) AssignmentStore {
+	if size <= 0 {
+		panic("size of assignment store must be positive")
+	}
+	return &memoryAssignmentStore{
+		size:    size,
+		entries: make(map[assignmentKey]*list.Element),
+		lru:     list.New(),
+	}
+}
+
+func (s *memoryAssignmentStore) Get(featureName, subject string) (Assignment, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, exists := s.entries[assignmentKey{featureName, subject}]
+	if !exists {
+		return Assignment{}, false
+	}
+	s.lru.MoveToFront(e)
+	return e.Value.(*assignmentEntry).assignment, true
+}
+
+func (s *memoryAssignmentStore) Set(featureName, subject string, assignment Assignment) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := assignmentKey{featureName, subject}
+	if e, exists := s.entries[key]; exists {
+		e.Value.(*assignmentEntry).assignment = assignment
+		s.lru.MoveToFront(e)
+		return nil
+	}
+	s.entries[key] = s.lru.PushFront(&assignmentEntry{key: key, assignment: assignment})
+	if s.lru.Len() > s.size {
+		e := s.lru.Back()
+		s.lru.Remove(e)
+		delete(s.entries, e.Value.(*assignmentEntry).key)
+	}
+	return nil
+}
+
+func (s *memoryAssignmentStore) Delete(featureName, subject string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := assignmentKey{featureName, subject}
+	if e, exists := s.entries[key]; exists {
+		s.lru.Remove(e)
+		delete(s.entries, key)
+	}
+	return nil
+}
+
+// minCompactRecords is the minimum number of records in the file of FileAssignmentStore to compact it.
+const minCompactRecords = 1024
+
+// FileAssignmentStore is an AssignmentStore which stores assignments in a file in JSON Lines format.
+// Each change of assignment is appended to the file as a record, and the file is compacted to the current assignments
+// when the number of records exceeds twice of them.
+type FileAssignmentStore struct {
+	name string
+	file *os.File
+	enc  *json.Encoder
+
+	// assignments by subject by feature name.
+	assignments map[string]map[string]Assignment
+
+	// number of assignments, and number of records in the file.
+	size    int
+	records int
+
+	mu sync.Mutex
+}
+
+// assignmentRecord represents a change of assignment in the file of FileAssignmentStore.
+type assignmentRecord struct {
+	FeatureName string `json:"feature"`
+	Subject     string `json:"subject"`
+
+	// assignment which has been set, or nil if it has been deleted.
+	Assignment *Assignment `json:"assignment,omitempty"`
+}
+
+// OpenFileAssignmentStore returns a new FileAssignmentStore which stores assignments in the file named name.
+// The assignments are loaded from the file if it exists.
+// The incomplete record at the end of file, which is written partially by a crash, is discarded.
+func OpenFileAssignmentStore(name string) (*FileAssignmentStore, error) {
+	s := &FileAssignmentStore{
+		name:        name,
+		assignments: make(map[string]map[string]Assignment),
+	}
+	truncated, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	if truncated {
+		if err := s.compact(); err != nil {
+			return nil, err
+		}
+		return s, nil
+	}
+	if err := s.open(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// load replays the records in the file. It returns true if the last record is incomplete.
+func (s *FileAssignmentStore) load() (truncated bool, err error) {
+	file, err := os.Open(s.name)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	defer file.Close()
+	dec := json.NewDecoder(file)
+	for {
+		var r assignmentRecord
+		if err := dec.Decode(&r); err != nil {
+			if err == io.EOF {
+				return false, nil
+			}
+			if err == io.ErrUnexpectedEOF {
+				return true, nil
+			}
+			return false, err
+		}
+		if r.Assignment != nil {
+			s.set(r.FeatureName, r.Subject, *r.Assignment)
+		} else {
+			s.delete(r.FeatureName, r.Subject)
+		}
+		s.records++
+	}
+}
+
+// open opens the file for appending records.
+func (s *FileAssignmentStore) open() error {
+	file, err := os.OpenFile(s.name, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	s.file, s.enc = file, json.NewEncoder(file)
+	return nil
+}
+
+func (s *FileAssignmentStore) Get(featureName, subject string) (Assignment, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	a, exists := s.assignments[featureName][subject]
+	return a, exists
+}
+
+func (s *FileAssignmentStore) Set(featureName, subject string, assignment Assignment) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.set(featureName, subject, assignment)
+	return s.append(&assignmentRecord{FeatureName: featureName, Subject: subject, Assignment: &assignment})
+}
+
+func (s *FileAssignmentStore) Delete(featureName, subject string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.delete(featureName, subject) {
+		return nil
+	}
+	return s.append(&assignmentRecord{FeatureName: featureName, Subject: subject})
+}
+
+// Close closes the file.
+func (s *FileAssignmentStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.file.Close()
+}
+
+// set sets the assignment in memory.
+func (s *FileAssignmentStore) set(featureName, subject string, assignment Assignment) {
+	if s.assignments[featureName] == nil {
+		s.assignments[featureName] = make(map[string]Assignment)
+	}
+	if _, exists := s.assignments[featureName][subject]; !exists {
+		s.size++
+	}
+	s.assignments[featureName][subject] = assignment
+}
+
+// delete deletes the assignment in memory, and returns whether it has existed.
+func (s *FileAssignmentStore) delete(featureName, subject string) bool {
+	if _, exists := s.assignments[featureName][subject]; !exists {
+		return false
+	}
+	delete(s.assignments[featureName], subject)
+	if len(s.assignments[featureName]) == 0 {
+		delete(s.assignments, featureName)
+	}
+	s.size--
+	return true
+}
+
+// append appends r to the file, and compacts the file if it has too many records.
+func (s *FileAssignmentStore) append(r *assignmentRecord) error {
+	if err := s.enc.Encode(r); err != nil {
+		return err
+	}
+	s.records++
+	if s.records >= minCompactRecords && s.records > 2*s.size {
+		return s.compact()
+	}
+	return nil
+}
+
+// compact rewrites the file with the records of the current assignments.
+func (s *FileAssignmentStore) compact() error {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for featureName, m := range s.assignments {
+		for subject, a := range m {
+			a := a
+			if err := enc.Encode(&assignmentRecord{FeatureName: featureName, Subject: subject, Assignment: &a}); err != nil {
+				return err
+			}
+		}
+	}
+	if err := writeFileAtomic(s.name, buf.Bytes()); err != nil {
+		return err
+	}
+	s.records = s.size
+	if s.file != nil {
+		s.file.Close()
+	}
+	return s.open()
+}
+
+// writeFileAtomic writes data to the file named name via a temporary file.
+func writeFileAtomic(name string, data []byte) error {
+	f, err := os.CreateTemp(filepath.Dir(name), filepath.Base(name)+".tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	return os.Rename(f.Name(), name)
+}
@@ -0,0 +1,183 @@
+package gocchan
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func Test_SetAssignmentStore(t *testing.T) {
+	defer Isolate()()
+	SetAssignmentStore(NewMemoryAssignmentStore(10))
+	feature := &TestFeature{t, "test", true, nil, nil}
+	AddFeature("test", feature)
+	if !ActiveIf("test", "subject1") {
+		t.Fatalf("ActiveIf returns false")
+	}
+	feature.active = false
+	if !ActiveIf("test", "subject1") {
+		t.Errorf("ActiveIf isn't sticky for subject1")
+	}
+	Invoke("subject1", "test", "Func1", func() {
+		t.Errorf("defaultFunc has been called by sticky assignment")
+	})
+	if ActiveIf("test", "subject2") {
+		t.Errorf("ActiveIf returns true for new subject")
+	}
+	if ActiveIf("test", 1) {
+		t.Errorf("ActiveIf returns true for unidentified subject")
+	}
+	if err := ResetAssignment("test", "subject1"); err != nil {
+		t.Fatal(err)
+	}
+	if ActiveIf("test", "subject1") {
+		t.Errorf("ActiveIf is still sticky after reset")
+	}
+
+	Override("test", true)
+	if !ActiveIf("test", "subject2") {
+		t.Errorf("override is ignored by sticky assignment")
+	}
+}
+
+func Test_SetAssignmentStore_variants(t *testing.T) {
+	defer Isolate()()
+	SetAssignmentStore(NewMemoryAssignmentStore(10))
+	AddFeature("experiment", &TestExperiment{TestFeature{t, "test", true, nil, nil}}, Variants(Variant{"A", 1}))
+	Invoke("subject1", "experiment", "Func", nil)
+	actual, _ := VariantOf("experiment", "subject1")
+	expected := "A"
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Expect %q, but %q", expected, actual)
+	}
+
+	feature := &TestExperiment{TestFeature{t, "test", true, nil, nil}}
+	AddFeature("experiment", feature, Variants(Variant{"A", 1}, Variant{"B", 1000000}))
+	Invoke("subject1", "experiment", "Func", nil)
+	actual, _ = VariantOf("experiment", "subject1")
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Expect %q, but %q", expected, actual)
+	}
+	if !reflect.DeepEqual(feature.calledBy, []string{"FuncA:subject1"}) {
+		t.Errorf("Expect %q, but %q", []string{"FuncA:subject1"}, feature.calledBy)
+	}
+
+	AddFeature("experiment", feature, Variants(Variant{"B", 1}))
+	actual, _ = VariantOf("experiment", "subject1")
+	expected = "B"
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Expect %q after the variant is removed, but %q", expected, actual)
+	}
+}
+
+func Test_NewMemoryAssignmentStore(t *testing.T) {
+	func() {
+		defer func() {
+			if err := recover(); err == nil {
+				t.Errorf("panic doesn't occurred by size of zero")
+			}
+		}()
+		NewMemoryAssignmentStore(0)
+	}()
+
+	store := NewMemoryAssignmentStore(2)
+	store.Set("test", "subject1", Assignment{Active: true})
+	store.Set("test", "subject2", Assignment{Active: false, Variant: "A"})
+	store.Get("test", "subject1")
+	store.Set("test", "subject3", Assignment{Active: true})
+	for _, v := range []struct {
+		subject  string
+		exists   bool
+		expected Assignment
+	}{
+		{"subject1", true, Assignment{Active: true}},
+		{"subject2", false, Assignment{}},
+		{"subject3", true, Assignment{Active: true}},
+	} {
+		actual, exists := store.Get("test", v.subject)
+		if exists != v.exists || !reflect.DeepEqual(actual, v.expected) {
+			t.Errorf("%v: expect %v and %v, but %v and %v", v.subject, v.expected, v.exists, actual, exists)
+		}
+	}
+	store.Delete("test", "subject1")
+	if _, exists := store.Get("test", "subject1"); exists {
+		t.Errorf("assignment hasn't been deleted")
+	}
+}
+
+func Test_OpenFileAssignmentStore(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "assignments.json")
+	store, err := OpenFileAssignmentStore(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Set("test", "subject1", Assignment{Active: true, Variant: "A"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Set("test", "subject2", Assignment{Active: true}); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Delete("test", "subject2"); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	store, err = OpenFileAssignmentStore(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	actual, exists := store.Get("test", "subject1")
+	expected := Assignment{Active: true, Variant: "A"}
+	if !exists || !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Expect %v, but %v", expected, actual)
+	}
+	if _, exists := store.Get("test", "subject2"); exists {
+		t.Errorf("deleted assignment has been loaded")
+	}
+}
+
+func Test_FileAssignmentStore_compact(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "assignments.json")
+	store, err := OpenFileAssignmentStore(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < minCompactRecords*3; i++ {
+		if err := store.Set("test", fmt.Sprintf("subject%d", i%10), Assignment{Active: i%2 == 0}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := store.Close(); err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := bytes.Count(b, []byte("\n")); n >= minCompactRecords {
+		t.Errorf("file hasn't been compacted: %d records", n)
+	}
+
+	// incomplete record at the end of file is discarded.
+	if err := os.WriteFile(name, append(b, `{"feature":"test","sub`...), 0644); err != nil {
+		t.Fatal(err)
+	}
+	store, err = OpenFileAssignmentStore(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	for i := 0; i < 10; i++ {
+		actual, exists := store.Get("test", fmt.Sprintf("subject%d", i))
+		expected := Assignment{Active: i%2 == 0}
+		if !exists || !reflect.DeepEqual(actual, expected) {
+			t.Errorf("subject%d: Expect %v, but %v", i, expected, actual)
+		}
+	}
+}
@@ -0,0 +1,190 @@
+package gocchan
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// AuditAction represents a kind of change of feature state.
+type AuditAction string
+
+const (
+	AuditAdd           AuditAction = "add"
+	AuditRemove        AuditAction = "remove"
+	AuditOverride      AuditAction = "override"
+	AuditClearOverride AuditAction = "clear-override"
+	AuditFault         AuditAction = "fault"
+	AuditResetFault    AuditAction = "reset-fault"
+	AuditRestore       AuditAction = "restore"
+)
+
+// SystemActor is the actor of the changes which are made by gocchan itself, such as faults.
+const SystemActor = "gocchan"
+
+// AuditEntry represents a change of feature state.
+type AuditEntry struct {
+	// time when the change was made.
+	Time time.Time `json:"time"`
+
+	// who made the change.
+	Actor string `json:"actor,omitempty"`
+
+	Action AuditAction `json:"action"`
+
+	// name of feature which was changed.
+	FeatureName string `json:"feature"`
+
+	// name of method which was changed if any.
+	FuncName string `json:"func,omitempty"`
+
+	// name of group which was changed instead of feature if any.
+	GroupName string `json:"group,omitempty"`
+
+	// reason why the change was made.
+	Reason string `json:"reason,omitempty"`
+
+	// state before and after the change.
+	Old string `json:"old,omitempty"`
+	New string `json:"new,omitempty"`
+}
+
+// AuditSink is an interface of sink of audit entries.
+type AuditSink interface {
+	// WriteAudit writes entry.
+	WriteAudit(entry *AuditEntry) error
+}
+
+// auditLog is a bounded ring of audit entries.
+type auditLog struct {
+	entries []AuditEntry
+
+	// index of the oldest entry when the ring is full.
+	next int
+
+	size int
+	sink AuditSink
+	mu   sync.Mutex
+}
+
+// defaultAuditLogSize is the default number of audit entries which are retained.
+const defaultAuditLogSize = 1000
+
+// global audit log.
+var audits = &auditLog{size: defaultAuditLogSize}
+
+// SetAuditLogSize sets the number of audit entries which are retained in memory.
+// The oldest entries are discarded when the number of entries exceeds n.
+// The default is 1000. If n isn't positive, it panic.
+func SetAuditLogSize(n int) {
+	if n <= 0 {
+		panic(fmt.Sprintf("audit log size must be positive: %d", n))
+	}
+	entries := AuditLog()
+	if len(entries) > n {
+		entries = entries[len(entries)-n:]
+	}
+	audits.mu.Lock()
+	defer audits.mu.Unlock()
+	audits.entries, audits.next, audits.size = entries, 0, n
+}
+
+// SetAuditSink sets the sink which the audit entries are written to in addition to memory.
+// The errors of sink are logged by the standard logger.
+// If sink is nil, the entries are retained only in memory.
+func SetAuditSink(sink AuditSink) {
+	audits.mu.Lock()
+	defer audits.mu.Unlock()
+	audits.sink = sink
+}
+
+// AuditLog returns the audit entries retained in memory from oldest to newest.
+func AuditLog() []AuditEntry {
+	audits.mu.Lock()
+	defer audits.mu.Unlock()
+	entries := make([]AuditEntry, 0, len(audits.entries))
+	entries = append(entries, audits.entries[audits.next:]...)
+	return append(entries, audits.entries[:audits.next]...)
+}
+
+// audit records a change of feature state by actor.
+func audit(actor Actor, action AuditAction, featureName, funcName, old, new string) {
+	record(actor, AuditEntry{Action: action, FeatureName: featureName, FuncName: funcName, Old: old, New: new})
+}
+
+// auditGroup records a change of group by actor.
+func auditGroup(actor Actor, action AuditAction, groupName, old, new string) {
+	record(actor, AuditEntry{Action: action, GroupName: groupName, Old: old, New: new})
+}
+
+// record records entry by actor to the audit log and the sink.
+func record(actor Actor, entry AuditEntry) {
+	entry.Time = now()
+	entry.Actor = actor.Name
+	entry.Reason = actor.Reason
+	audits.mu.Lock()
+	defer audits.mu.Unlock()
+	if len(audits.entries) < audits.size {
+		audits.entries = append(audits.entries, entry)
+	} else {
+		audits.entries[audits.next] = entry
+		audits.next = (audits.next + 1) % audits.size
+	}
+	if audits.sink != nil {
+		if err := audits.sink.WriteAudit(&entry); err != nil {
+			log.Printf("gocchan: failed to write audit entry: %v", err)
+		}
+	}
+}
+
+// Actor represents who makes the changes of feature state and why.
+// The changes made through Actor are recorded in the audit log with its name and reason.
+type Actor struct {
+	// name of actor.
+	Name string
+
+	// reason of the changes.
+	Reason string
+}
+
+// As returns an Actor with name and reason.
+func As(name, reason string) Actor {
+	return Actor{Name: name, Reason: reason}
+}
+
+// AddFeature is like AddFeature of the package but records the actor.
+func (a Actor) AddFeature(name string, feature Feature, opts ...Option) {
+	addFeature(a, name, feature, opts...)
+}
+
+// RemoveFeature is like RemoveFeature of the package but records the actor.
+func (a Actor) RemoveFeature(featureName string) {
+	removeFeature(a, featureName)
+}
+
+// Override is like Override of the package but records the actor.
+func (a Actor) Override(featureName string, active bool) {
+	override(a, featureName, active)
+}
+
+// ClearOverride is like ClearOverride of the package but records the actor.
+func (a Actor) ClearOverride(featureName string) {
+	clearOverride(a, featureName)
+}
+
+// ResetFault is like ResetFault of the package but records the actor.
+func (a Actor) ResetFault(featureName string, funcNames ...string) {
+	resetFault(a, featureName, funcNames...)
+}
+
+// activation returns the state of forced activation for the audit log.
+func activation(o OverrideState, exists bool) string {
+	switch {
+	case !exists:
+		return ""
+	case o.Active:
+		return "active"
+	}
+	return "inactive"
+}
@@ -0,0 +1,92 @@
+package gocchan
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func Test_AuditLog(t *testing.T) {
+	defer Isolate()()
+	current := time.Date(2014, 6, 1, 0, 0, 0, 0, time.UTC)
+	SetClock(ClockFunc(func() time.Time { return current }))
+	var buf bytes.Buffer
+	SetAuditSink(NewJSONLinesSink(&buf))
+	admin := As("naoina", "incident")
+	admin.AddFeature("test", &TestFeature{t, "test", true, nil, nil})
+	Invoke("ctx", "test", "FuncPanic", nil)
+	admin.Override("test", false)
+	OverrideWithReason("test", true, "recovered")
+	admin.ClearOverride("test")
+	admin.ResetFault("test", "FuncPanic")
+	ResetFault("test")
+	AddFeature("test", &TestFeature{t, "test", true, nil, nil})
+	admin.RemoveFeature("test")
+	RemoveFeature("test")
+	actual := AuditLog()
+	expected := []AuditEntry{
+		{Time: current, Actor: "naoina", Action: AuditAdd, FeatureName: "test", Reason: "incident", New: "added"},
+		{Time: current, Actor: SystemActor, Action: AuditFault, FeatureName: "test", FuncName: "FuncPanic", Reason: "1 consecutive faults", Old: "active", New: "fault"},
+		{Time: current, Actor: "naoina", Action: AuditOverride, FeatureName: "test", Reason: "incident", New: "inactive"},
+		{Time: current, Action: AuditOverride, FeatureName: "test", Reason: "recovered", Old: "inactive", New: "active"},
+		{Time: current, Actor: "naoina", Action: AuditClearOverride, FeatureName: "test", Reason: "incident", Old: "active"},
+		{Time: current, Actor: "naoina", Action: AuditResetFault, FeatureName: "test", FuncName: "FuncPanic", Reason: "incident", Old: "fault", New: "active"},
+		{Time: current, Action: AuditResetFault, FeatureName: "test", Old: "active", New: "active"},
+		{Time: current, Action: AuditAdd, FeatureName: "test", Old: "added", New: "added"},
+		{Time: current, Actor: "naoina", Action: AuditRemove, FeatureName: "test", Reason: "incident", Old: "added"},
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Expect %v, but %v", expected, actual)
+	}
+	var written []AuditEntry
+	dec := json.NewDecoder(&buf)
+	for dec.More() {
+		var entry AuditEntry
+		if err := dec.Decode(&entry); err != nil {
+			t.Fatal(err)
+		}
+		written = append(written, entry)
+	}
+	if !reflect.DeepEqual(written, expected) {
+		t.Errorf("Expect %v, but %v", expected, written)
+	}
+	if IsActive("test") {
+		t.Errorf("removed feature is active")
+	}
+}
+
+func Test_SetAuditLogSize(t *testing.T) {
+	func() {
+		defer func() {
+			if err := recover(); err == nil {
+				t.Errorf("panic doesn't occurred by size of zero")
+			}
+		}()
+		SetAuditLogSize(0)
+	}()
+
+	defer Isolate()()
+	SetAuditLogSize(3)
+	for _, name := range []string{"a", "b", "c", "d", "e"} {
+		Override(name, true)
+	}
+	var actual []string
+	for _, entry := range AuditLog() {
+		actual = append(actual, entry.FeatureName)
+	}
+	expected := []string{"c", "d", "e"}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Expect %v, but %v", expected, actual)
+	}
+	SetAuditLogSize(2)
+	actual = nil
+	for _, entry := range AuditLog() {
+		actual = append(actual, entry.FeatureName)
+	}
+	expected = []string{"d", "e"}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Expect %v, but %v", expected, actual)
+	}
+}
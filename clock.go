@@ -0,0 +1,41 @@
+package gocchan
+
+import "time"
+
+// Clock is an interface of clock which is used to get the current time.
+type Clock interface {
+	// Now returns the current time.
+	Now() time.Time
+}
+
+// ClockFunc is an adapter to allow the use of ordinary function as Clock.
+type ClockFunc func() time.Time
+
+// Now returns f().
+func (f ClockFunc) Now() time.Time {
+	return f()
+}
+
+// systemClock is a Clock which returns the current time of system.
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+// global clock.
+var clock Clock = systemClock{}
+
+// SetClock sets the global clock which is used for expiry, exposures and schedules.
+// If c is nil, the clock of system is used.
+func SetClock(c Clock) {
+	if c == nil {
+		c = systemClock{}
+	}
+	clock = c
+}
+
+// now returns the current time of global clock.
+func now() time.Time {
+	return clock.Now()
+}
@@ -0,0 +1,394 @@
+// Command gocchangen generates the strongly typed wrapper functions of gocchan.Invoke for the methods of features.
+//
+// It scans the Go files in the current directory for the types implementing gocchan.Feature
+// that are added by gocchan.AddFeature with a constant name, such as
+//
+//	gocchan.AddFeature("hello", &HelloFeature{})
+//
+// except the experiments which are added with gocchan.Variants,
+// and generates the following function for each exported method which takes exactly one argument except variadic one.
+//
+//	func InvokeHelloSay1(ctx bool, defaultFunc func(), options ...interface{}) {
+//		gocchan.Invoke(ctx, "hello", "Say1", defaultFunc, options...)
+//	}
+//
+// The name of function consists of "Invoke", the type name without the "Feature" suffix and the method name.
+// Typically, it is used by go generate:
+//
+//	//go:generate gocchangen
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"log"
+	"os"
+	pathpkg "path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+const gocchanPath = "github.com/naoina/gocchan"
+
+var output = flag.String("output", "gocchan_invoke.go", "output file name")
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("gocchangen: ")
+	flag.Parse()
+	dir := "."
+	if flag.NArg() > 0 {
+		dir = flag.Arg(0)
+	}
+	src, err := generate(dir, *output)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, *output), src, 0644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// feature represents a feature type and its methods which the wrappers are generated for.
+type feature struct {
+	// name of feature.
+	name string
+
+	// name of type.
+	typeName string
+
+	methods []*ast.FuncDecl
+
+	// file which the method is declared in, by method name.
+	files map[string]*ast.File
+}
+
+// generate returns the generated source of the package in dir.
+// The file named output is excluded from the scan.
+func generate(dir, output string) ([]byte, error) {
+	fset := token.NewFileSet()
+	paths, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	if err != nil {
+		return nil, err
+	}
+	var files []*ast.File
+	for _, path := range paths {
+		name := filepath.Base(path)
+		if name == output || strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+		file, err := parser.ParseFile(fset, path, nil, 0)
+		if err != nil {
+			return nil, err
+		}
+		if len(files) > 0 && file.Name.Name != files[0].Name.Name {
+			return nil, fmt.Errorf("multiple packages found in %s: %s and %s", dir, files[0].Name.Name, file.Name.Name)
+		}
+		files = append(files, file)
+	}
+	features, err := findFeatures(files)
+	if err != nil {
+		return nil, err
+	}
+	if len(features) == 0 {
+		return nil, fmt.Errorf("no features found in %s", dir)
+	}
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "// Code generated by gocchangen. DO NOT EDIT.\n\n")
+	fmt.Fprintf(&buf, "package %s\n\n", files[0].Name.Name)
+	imports := map[string]string{"gocchan": strconv.Quote(gocchanPath)}
+	var body bytes.Buffer
+	for _, f := range features {
+		for _, method := range f.methods {
+			param := method.Type.Params.List[0]
+			var typ bytes.Buffer
+			if err := printer.Fprint(&typ, fset, param.Type); err != nil {
+				return nil, err
+			}
+			for name, path := range usedImports(f.files[method.Name.Name], param.Type) {
+				imports[name] = path
+			}
+			funcName := "Invoke" + strings.TrimSuffix(f.typeName, "Feature") + method.Name.Name
+			fmt.Fprintf(&body, "\n// %s invokes the method %s of the feature %q.\n", funcName, method.Name.Name, f.name)
+			fmt.Fprintf(&body, "// See gocchan.Invoke for details.\n")
+			fmt.Fprintf(&body, "func %s(ctx %s, defaultFunc func(), options ...interface{}) {\n", funcName, typ.String())
+			fmt.Fprintf(&body, "\tgocchan.Invoke(ctx, %q, %q, defaultFunc, options...)\n", f.name, method.Name.Name)
+			fmt.Fprintf(&body, "}\n")
+		}
+	}
+	names := make([]string, 0, len(imports))
+	for name := range imports {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	fmt.Fprintf(&buf, "import (\n")
+	for _, name := range names {
+		if path, _ := strconv.Unquote(imports[name]); importName(path) == name {
+			fmt.Fprintf(&buf, "\t%s\n", imports[name])
+		} else {
+			fmt.Fprintf(&buf, "\t%s %s\n", name, imports[name])
+		}
+	}
+	fmt.Fprintf(&buf, ")\n")
+	buf.Write(body.Bytes())
+	return format.Source(buf.Bytes())
+}
+
+// findFeatures returns the features in files sorted by name.
+// It returns an error if a type is added as multiple features or a feature is added with multiple types,
+// since the names of their wrappers can't be determined.
+func findFeatures(files []*ast.File) ([]*feature, error) {
+	// names of features by type name.
+	names := make(map[string]map[string]bool)
+	// type names by feature name.
+	typeNames := make(map[string]string)
+	experiments := make(map[string]bool)
+	types := make(map[string]*feature)
+	var conflict error
+	for _, file := range files {
+		ast.Inspect(file, func(n ast.Node) bool {
+			call, ok := n.(*ast.CallExpr)
+			if !ok || !isAddFeature(file, call) || len(call.Args) < 2 {
+				return true
+			}
+			lit, ok := call.Args[0].(*ast.BasicLit)
+			if !ok || lit.Kind != token.STRING {
+				return true
+			}
+			name, err := strconv.Unquote(lit.Value)
+			if err != nil {
+				return true
+			}
+			typeName := typeNameOf(call.Args[1])
+			if typeName == "" {
+				return true
+			}
+			if hasVariants(call) {
+				experiments[typeName] = true
+				return true
+			}
+			if names[typeName] == nil {
+				names[typeName] = make(map[string]bool)
+			}
+			names[typeName][name] = true
+			if other, exists := typeNames[name]; exists && other != typeName {
+				conflict = fmt.Errorf("feature %q is added with multiple types: %s and %s", name, other, typeName)
+			}
+			typeNames[name] = typeName
+			return true
+		})
+		for _, decl := range file.Decls {
+			method, ok := decl.(*ast.FuncDecl)
+			if !ok || method.Recv == nil || len(method.Recv.List) != 1 {
+				continue
+			}
+			typeName := typeNameOf(method.Recv.List[0].Type)
+			if typeName == "" {
+				continue
+			}
+			f := types[typeName]
+			if f == nil {
+				f = &feature{typeName: typeName, files: make(map[string]*ast.File)}
+				types[typeName] = f
+			}
+			f.methods = append(f.methods, method)
+			f.files[method.Name.Name] = file
+		}
+	}
+	if conflict != nil {
+		return nil, conflict
+	}
+	var features []*feature
+	for typeName, f := range types {
+		if !isFeature(f) {
+			continue
+		}
+		if experiments[typeName] {
+			log.Printf("feature %s is skipped since it is an experiment", typeName)
+			continue
+		}
+		if len(names[typeName]) == 0 {
+			log.Printf("feature %s is skipped since it hasn't been added with a constant name", typeName)
+			continue
+		}
+		if len(names[typeName]) > 1 {
+			return nil, fmt.Errorf("type %s is added as multiple features: %s", typeName, strings.Join(sortedKeys(names[typeName]), ", "))
+		}
+		f.name = sortedKeys(names[typeName])[0]
+		var methods []*ast.FuncDecl
+		for _, method := range f.methods {
+			if method.Name.IsExported() && method.Name.Name != "ActiveIf" && numParams(method) == 1 && !isVariadic(method) {
+				methods = append(methods, method)
+			}
+		}
+		sort.Slice(methods, func(i, j int) bool {
+			return methods[i].Name.Name < methods[j].Name.Name
+		})
+		f.methods = methods
+		features = append(features, f)
+	}
+	sort.Slice(features, func(i, j int) bool {
+		return features[i].name < features[j].name
+	})
+	return features, nil
+}
+
+// sortedKeys returns the keys of m sorted.
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// isFeature returns whether f has the ActiveIf method of gocchan.Feature.
+func isFeature(f *feature) bool {
+	for _, method := range f.methods {
+		if method.Name.Name != "ActiveIf" || numParams(method) != 2 {
+			continue
+		}
+		results := method.Type.Results
+		if results == nil || len(results.List) != 1 {
+			continue
+		}
+		if ident, ok := results.List[0].Type.(*ast.Ident); ok && ident.Name == "bool" {
+			return true
+		}
+	}
+	return false
+}
+
+// numParams returns the number of parameters of method.
+func numParams(method *ast.FuncDecl) int {
+	n := 0
+	for _, field := range method.Type.Params.List {
+		if len(field.Names) == 0 {
+			n++
+		} else {
+			n += len(field.Names)
+		}
+	}
+	return n
+}
+
+// isVariadic returns whether method has a variadic parameter.
+func isVariadic(method *ast.FuncDecl) bool {
+	params := method.Type.Params.List
+	if len(params) == 0 {
+		return false
+	}
+	_, ok := params[len(params)-1].Type.(*ast.Ellipsis)
+	return ok
+}
+
+// isAddFeature returns whether call is a call of gocchan.AddFeature in file.
+func isAddFeature(file *ast.File, call *ast.CallExpr) bool {
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok || sel.Sel.Name != "AddFeature" {
+		return false
+	}
+	x, ok := sel.X.(*ast.Ident)
+	if !ok {
+		return false
+	}
+	path, exists := usedImports(file, sel)[x.Name]
+	return exists && path == strconv.Quote(gocchanPath)
+}
+
+// hasVariants returns whether the call of AddFeature has the gocchan.Variants option.
+func hasVariants(call *ast.CallExpr) bool {
+	for _, arg := range call.Args[2:] {
+		opt, ok := arg.(*ast.CallExpr)
+		if !ok {
+			continue
+		}
+		if sel, ok := opt.Fun.(*ast.SelectorExpr); ok && sel.Sel.Name == "Variants" {
+			return true
+		}
+	}
+	return false
+}
+
+// typeNameOf returns the name of type of expr such as T{}, &T{}, new(T), T and *T.
+// It returns empty if the type can't be determined.
+func typeNameOf(expr ast.Expr) string {
+	switch e := expr.(type) {
+	case *ast.Ident:
+		return e.Name
+	case *ast.StarExpr:
+		return typeNameOf(e.X)
+	case *ast.UnaryExpr:
+		if e.Op == token.AND {
+			return typeNameOf(e.X)
+		}
+	case *ast.CompositeLit:
+		return typeNameOf(e.Type)
+	case *ast.ParenExpr:
+		return typeNameOf(e.X)
+	case *ast.CallExpr:
+		if ident, ok := e.Fun.(*ast.Ident); ok && ident.Name == "new" && len(e.Args) == 1 {
+			return typeNameOf(e.Args[0])
+		}
+	}
+	return ""
+}
+
+// usedImports returns the quoted import paths by package name which are referred in expr of file.
+func usedImports(file *ast.File, expr ast.Expr) map[string]string {
+	imports := make(map[string]string)
+	ast.Inspect(expr, func(n ast.Node) bool {
+		sel, ok := n.(*ast.SelectorExpr)
+		if !ok {
+			return true
+		}
+		x, ok := sel.X.(*ast.Ident)
+		if !ok {
+			return true
+		}
+		for _, spec := range file.Imports {
+			path, err := strconv.Unquote(spec.Path.Value)
+			if err != nil {
+				continue
+			}
+			name := importName(path)
+			if spec.Name != nil {
+				name = spec.Name.Name
+			}
+			if name == x.Name {
+				imports[name] = spec.Path.Value
+			}
+		}
+		return true
+	})
+	return imports
+}
+
+// importName returns the assumed package name of the import path without an explicit name.
+// It is the last element of path without the "go-" prefix and the suffix from the first non-identifier character,
+// and the major version element such as "v2" is skipped. e.g. "yaml" for "gopkg.in/yaml.v2" and "github.com/go-yaml/yaml/v3".
+func importName(path string) string {
+	base := pathpkg.Base(path)
+	if strings.HasPrefix(base, "v") {
+		if _, err := strconv.Atoi(base[1:]); err == nil && pathpkg.Dir(path) != "." {
+			base = pathpkg.Base(pathpkg.Dir(path))
+		}
+	}
+	base = strings.TrimPrefix(base, "go-")
+	if i := strings.IndexFunc(base, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_'
+	}); i >= 0 {
+		base = base[:i]
+	}
+	return base
+}
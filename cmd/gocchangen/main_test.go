@@ -0,0 +1,172 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+const testSource = `package hello
+
+import (
+	"net/http"
+
+	"github.com/naoina/gocchan"
+	"gopkg.in/yaml.v2"
+)
+
+type HelloFeature struct{}
+
+func (f *HelloFeature) ActiveIf(context interface{}, options ...interface{}) bool {
+	return true
+}
+
+func (f *HelloFeature) Say1(context bool) {}
+
+func (f *HelloFeature) Say2(r *http.Request) {}
+
+func (f *HelloFeature) Say3(m yaml.MapSlice) {}
+
+func (f *HelloFeature) SayTwo(context interface{}, other interface{}) {}
+
+func (f *HelloFeature) SayVariadic(contexts ...interface{}) {}
+
+func (f *HelloFeature) say(context interface{}) {}
+
+type NotAddedFeature struct{}
+
+func (f NotAddedFeature) ActiveIf(context interface{}, options ...interface{}) bool {
+	return true
+}
+
+func (f NotAddedFeature) Say(context interface{}) {}
+
+type ExperimentFeature struct{}
+
+func (f ExperimentFeature) ActiveIf(context interface{}, options ...interface{}) bool {
+	return true
+}
+
+func (f ExperimentFeature) SayA(context interface{}) {}
+
+type NotFeature struct{}
+
+func (f NotFeature) Say(context interface{}) {}
+
+func init() {
+	gocchan.AddFeature("hello", &HelloFeature{})
+	gocchan.AddFeature("notfeature", NotFeature{})
+	gocchan.AddFeature("experiment", ExperimentFeature{}, gocchan.Variants(gocchan.Variant{"A", 1}))
+}
+`
+
+const testExpected = `// Code generated by gocchangen. DO NOT EDIT.
+
+package hello
+
+import (
+	"github.com/naoina/gocchan"
+	"gopkg.in/yaml.v2"
+	"net/http"
+)
+
+// InvokeHelloSay1 invokes the method Say1 of the feature "hello".
+// See gocchan.Invoke for details.
+func InvokeHelloSay1(ctx bool, defaultFunc func(), options ...interface{}) {
+	gocchan.Invoke(ctx, "hello", "Say1", defaultFunc, options...)
+}
+
+// InvokeHelloSay2 invokes the method Say2 of the feature "hello".
+// See gocchan.Invoke for details.
+func InvokeHelloSay2(ctx *http.Request, defaultFunc func(), options ...interface{}) {
+	gocchan.Invoke(ctx, "hello", "Say2", defaultFunc, options...)
+}
+
+// InvokeHelloSay3 invokes the method Say3 of the feature "hello".
+// See gocchan.Invoke for details.
+func InvokeHelloSay3(ctx yaml.MapSlice, defaultFunc func(), options ...interface{}) {
+	gocchan.Invoke(ctx, "hello", "Say3", defaultFunc, options...)
+}
+`
+
+func Test_generate(t *testing.T) {
+	dir := t.TempDir()
+	for name, src := range map[string]string{
+		"hello.go":          testSource,
+		"hello_test.go":     "package hello_test\n",
+		"gocchan_invoke.go": "package invalid\n",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	src, err := generate(dir, "gocchan_invoke.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	actual := string(src)
+	expected := testExpected
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Expect %q, but %q", expected, actual)
+	}
+}
+
+func Test_importName(t *testing.T) {
+	for path, expected := range map[string]string{
+		"net/http":                        "http",
+		"gopkg.in/yaml.v2":                "yaml",
+		"github.com/go-yaml/yaml/v3":      "yaml",
+		"github.com/mattn/go-sqlite3":     "sqlite3",
+		"github.com/naoina/go-stringutil": "stringutil",
+		"v2":                              "v2",
+	} {
+		actual := importName(path)
+		if !reflect.DeepEqual(actual, expected) {
+			t.Errorf("%v: Expect %q, but %q", path, expected, actual)
+		}
+	}
+}
+
+func Test_generate_noFeatures(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "a.go"), []byte("package a\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := generate(dir, "gocchan_invoke.go"); err == nil {
+		t.Errorf("error hasn't been returned by no features")
+	}
+}
+
+func Test_generate_multipleFeatures(t *testing.T) {
+	for _, init := range []string{
+		`gocchan.AddFeature("hello1", &HelloFeature{}); gocchan.AddFeature("hello2", &HelloFeature{})`,
+		`gocchan.AddFeature("hello", &HelloFeature{}); gocchan.AddFeature("hello", &OtherFeature{})`,
+	} {
+		dir := t.TempDir()
+		src := `package hello
+
+import "github.com/naoina/gocchan"
+
+type HelloFeature struct{}
+
+func (f *HelloFeature) ActiveIf(context interface{}, options ...interface{}) bool {
+	return true
+}
+
+type OtherFeature struct{}
+
+func (f *OtherFeature) ActiveIf(context interface{}, options ...interface{}) bool {
+	return true
+}
+
+func init() { ` + init + ` }
+`
+		if err := os.WriteFile(filepath.Join(dir, "hello.go"), []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := generate(dir, "gocchan_invoke.go"); err == nil {
+			t.Errorf("error hasn't been returned by %v", init)
+		}
+	}
+}
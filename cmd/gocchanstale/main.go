@@ -0,0 +1,415 @@
+// Command gocchanstale reports the stale feature flags.
+//
+// It scans the Go files under the given directories (default is the current directory) recursively
+// for the calls of gocchan.AddFeature, gocchan.Invoke, gocchan.InvokeTimeout, gocchan.InvokeArgs, gocchan.ActiveIf
+// and gocchan.IsActive with a constant name,
+// and reports the following features.
+//
+//   - registered by AddFeature, but never invoked.
+//   - invoked, but never registered by AddFeature.
+//   - past its expiry date which is given by the config files or the gocchan.Expires option of AddFeature.
+//
+// The AddFeature methods, such as of gocchan.Actor and gocchantest.Registry, are also regarded as AddFeature
+// in the files which import gocchan or gocchantest, since the receiver isn't resolved by its type.
+// The expiry date is read only from gocchan.Expires with time.Date of constant year, month and day.
+//
+// The template files which have the extensions given by -templates are also scanned
+// for featureActive and featureRender of gocchan.FuncMap, which are regarded as invocations.
+// Only the helpers which are called with a constant name as the first argument are found,
+// so the helpers called by the call function or with a name from the pipeline aren't counted.
+//
+// The config file is a JSON file such as
+//
+//	{
+//		"features": {
+//			"hello": {"expires": "2014-12-31"}
+//		}
+//	}
+//
+// The features in the config file are regarded as registered.
+// It exits with status 1 if any stale features are found.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const gocchanPath = "github.com/naoina/gocchan"
+
+// configFiles is a list of config file names.
+type configFiles []string
+
+func (c *configFiles) String() string {
+	return strings.Join(*c, ",")
+}
+
+func (c *configFiles) Set(s string) error {
+	*c = append(*c, s)
+	return nil
+}
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("gocchanstale: ")
+	var configs configFiles
+	flag.Var(&configs, "config", "config file name (can be specified multiple times)")
+	tests := flag.Bool("tests", false, "also scan the test files")
+	templates := flag.String("templates", ".tmpl,.html,.gohtml", "comma-separated extensions of template files")
+	flag.Parse()
+	dirs := flag.Args()
+	if len(dirs) == 0 {
+		dirs = []string{"."}
+	}
+	u := newUsage()
+	for _, dir := range dirs {
+		if err := u.scan(dir, *tests, strings.Split(*templates, ",")); err != nil {
+			log.Fatal(err)
+		}
+	}
+	c := &config{}
+	for _, name := range configs {
+		if err := c.load(name); err != nil {
+			log.Fatal(err)
+		}
+	}
+	stales := check(u, c, time.Now())
+	for _, stale := range stales {
+		fmt.Println(stale)
+	}
+	if len(stales) > 0 {
+		os.Exit(1)
+	}
+}
+
+// usage represents the call sites of features.
+type usage struct {
+	// positions of AddFeature calls by feature name.
+	registered map[string][]token.Position
+
+	// positions of Invoke, InvokeTimeout, InvokeArgs, ActiveIf and IsActive calls,
+	// and featureActive and featureRender in templates by feature name.
+	invoked map[string][]token.Position
+
+	// expiry dates given by the Expires option of AddFeature by feature name.
+	expires map[string]expiry
+}
+
+// expiry represents an expiry date of feature.
+type expiry struct {
+	// expiry date in "2006-01-02" format.
+	date string
+
+	// position where the expiry date is given.
+	pos token.Position
+}
+
+func newUsage() *usage {
+	return &usage{
+		registered: make(map[string][]token.Position),
+		invoked:    make(map[string][]token.Position),
+		expires:    make(map[string]expiry),
+	}
+}
+
+// scan scans the Go files and the template files which have any of the extensions exts under root.
+// The directories named vendor or testdata, and starting with "." or "_" are skipped.
+func (u *usage) scan(root string, tests bool, exts []string) error {
+	fset := token.NewFileSet()
+	return filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		name := info.Name()
+		if info.IsDir() {
+			if path != root && (name == "vendor" || name == "testdata" || strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_")) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		for _, ext := range exts {
+			if ext != "" && filepath.Ext(name) == ext {
+				return u.scanTemplate(path)
+			}
+		}
+		if !strings.HasSuffix(name, ".go") || (!tests && strings.HasSuffix(name, "_test.go")) {
+			return nil
+		}
+		file, err := parser.ParseFile(fset, path, nil, 0)
+		if err != nil {
+			return err
+		}
+		u.scanFile(fset, file)
+		return nil
+	})
+}
+
+// scanFile records the call sites of features in file.
+func (u *usage) scanFile(fset *token.FileSet, file *ast.File) {
+	pkgName := importName(file, gocchanPath)
+	if pkgName == "" && importName(file, gocchanPath+"/gocchantest") == "" {
+		return
+	}
+	timeName := importName(file, "time")
+	ast.Inspect(file, func(n ast.Node) bool {
+		call, ok := n.(*ast.CallExpr)
+		if !ok {
+			return true
+		}
+		sel, ok := call.Fun.(*ast.SelectorExpr)
+		if !ok {
+			return true
+		}
+		var arg int
+		var sites map[string][]token.Position
+		switch {
+		case sel.Sel.Name == "AddFeature":
+			// gocchan.AddFeature, or the AddFeature method such as of gocchan.Actor and gocchantest.Registry.
+			arg, sites = 0, u.registered
+		case !isSelector(sel, pkgName, sel.Sel.Name):
+			return true
+		case sel.Sel.Name == "ActiveIf" || sel.Sel.Name == "IsActive":
+			arg, sites = 0, u.invoked
+		case sel.Sel.Name == "Invoke" || sel.Sel.Name == "InvokeTimeout" || sel.Sel.Name == "InvokeArgs":
+			arg, sites = 1, u.invoked
+		default:
+			return true
+		}
+		if len(call.Args) <= arg {
+			return true
+		}
+		lit, ok := call.Args[arg].(*ast.BasicLit)
+		if !ok || lit.Kind != token.STRING {
+			return true
+		}
+		name, err := strconv.Unquote(lit.Value)
+		if err != nil {
+			return true
+		}
+		sites[name] = append(sites[name], fset.Position(call.Pos()))
+		if sel.Sel.Name == "AddFeature" {
+			for _, opt := range call.Args[arg+1:] {
+				if date, ok := expiresOption(opt, pkgName, timeName); ok {
+					u.expires[name] = expiry{date: date, pos: fset.Position(opt.Pos())}
+				}
+			}
+		}
+		return true
+	})
+}
+
+// helperPattern matches featureActive and featureRender in templates with a constant feature name.
+var helperPattern = regexp.MustCompile(`\b(?:featureActive|featureRender)\s+("(?:[^"\\\n]|\\.)*"|` + "`[^`]*`)")
+
+// scanTemplate records the call sites of featureActive and featureRender in the template file.
+func (u *usage) scanTemplate(path string) error {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	for _, m := range helperPattern.FindAllSubmatchIndex(b, -1) {
+		name, err := strconv.Unquote(string(b[m[2]:m[3]]))
+		if err != nil {
+			continue
+		}
+		line := bytes.Count(b[:m[0]], []byte("\n")) + 1
+		column := m[0] - bytes.LastIndexByte(b[:m[0]], '\n')
+		u.invoked[name] = append(u.invoked[name], token.Position{Filename: path, Offset: m[0], Line: line, Column: column})
+	}
+	return nil
+}
+
+// expiresOption returns the expiry date in "2006-01-02" format if expr is gocchan.Expires with time.Date
+// of constant year, month and day.
+func expiresOption(expr ast.Expr, pkgName, timeName string) (string, bool) {
+	call, ok := expr.(*ast.CallExpr)
+	if !ok || !isSelector(call.Fun, pkgName, "Expires") || len(call.Args) != 1 {
+		return "", false
+	}
+	date, ok := call.Args[0].(*ast.CallExpr)
+	if !ok || !isSelector(date.Fun, timeName, "Date") || len(date.Args) < 3 {
+		return "", false
+	}
+	var ymd [3]int
+	for i, arg := range date.Args[:3] {
+		if lit, ok := arg.(*ast.BasicLit); ok && lit.Kind == token.INT {
+			n, err := strconv.Atoi(lit.Value)
+			if err != nil {
+				return "", false
+			}
+			ymd[i] = n
+			continue
+		}
+		sel, ok := arg.(*ast.SelectorExpr)
+		if !ok || i != 1 || !isSelector(sel, timeName, sel.Sel.Name) {
+			return "", false
+		}
+		for m := time.January; m <= time.December; m++ {
+			if m.String() == sel.Sel.Name {
+				ymd[i] = int(m)
+			}
+		}
+		if ymd[i] == 0 {
+			return "", false
+		}
+	}
+	return time.Date(ymd[0], time.Month(ymd[1]), ymd[2], 0, 0, 0, 0, time.UTC).Format("2006-01-02"), true
+}
+
+// isSelector returns whether expr is a selector of name on the identifier x.
+func isSelector(expr ast.Expr, x, name string) bool {
+	sel, ok := expr.(*ast.SelectorExpr)
+	if !ok || x == "" || sel.Sel.Name != name {
+		return false
+	}
+	ident, ok := sel.X.(*ast.Ident)
+	return ok && ident.Name == x
+}
+
+// importName returns the name of package path which is imported in file.
+// It returns empty if the package isn't imported.
+func importName(file *ast.File, path string) string {
+	for _, spec := range file.Imports {
+		if p, err := strconv.Unquote(spec.Path.Value); err != nil || p != path {
+			continue
+		}
+		if spec.Name != nil {
+			return spec.Name.Name
+		}
+		return path[strings.LastIndex(path, "/")+1:]
+	}
+	return ""
+}
+
+// config represents the metadata of features.
+type config struct {
+	Features map[string]*featureConfig `json:"features"`
+}
+
+// featureConfig represents the metadata of a feature.
+type featureConfig struct {
+	// expiry date in "2006-01-02" format.
+	Expires string `json:"expires"`
+
+	// name of config file which the feature is defined in.
+	file string
+}
+
+// load loads the config file and merges it.
+func (c *config) load(name string) error {
+	b, err := os.ReadFile(name)
+	if err != nil {
+		return err
+	}
+	var loaded config
+	if err := json.Unmarshal(b, &loaded); err != nil {
+		return fmt.Errorf("%s: %v", name, err)
+	}
+	if c.Features == nil {
+		c.Features = make(map[string]*featureConfig)
+	}
+	for featureName, f := range loaded.Features {
+		f.file = name
+		c.Features[featureName] = f
+	}
+	return nil
+}
+
+// stale represents a stale feature.
+type stale struct {
+	// position where the stale feature is found.
+	// Only the file name is given if it is found in the config file.
+	pos token.Position
+
+	msg string
+}
+
+// check returns the messages of stale features sorted by position.
+func check(u *usage, c *config, now time.Time) []string {
+	var stales []stale
+	add := func(pos token.Position, format string, args ...interface{}) {
+		stales = append(stales, stale{pos: pos, msg: fmt.Sprintf(format, args...)})
+	}
+	for name, sites := range u.registered {
+		if _, exists := u.invoked[name]; !exists {
+			for _, pos := range sites {
+				add(pos, "feature `%s` is registered but never invoked", name)
+			}
+		}
+	}
+	for name, sites := range u.invoked {
+		if _, exists := u.registered[name]; exists {
+			continue
+		}
+		if _, exists := c.Features[name]; exists {
+			continue
+		}
+		for _, pos := range sites {
+			add(pos, "feature `%s` is invoked but never registered", name)
+		}
+	}
+	for name, f := range c.Features {
+		_, registered := u.registered[name]
+		if _, invoked := u.invoked[name]; !registered && !invoked {
+			add(token.Position{Filename: f.file}, "feature `%s` is registered but never invoked", name)
+		}
+	}
+	// the expiry date in the config file takes precedence over the Expires option.
+	expiries := make(map[string]expiry)
+	for name, e := range u.expires {
+		expiries[name] = e
+	}
+	for name, f := range c.Features {
+		if f.Expires != "" {
+			expiries[name] = expiry{date: f.Expires, pos: token.Position{Filename: f.file}}
+		}
+	}
+	for name, e := range expiries {
+		expires, err := time.Parse("2006-01-02", e.date)
+		if err != nil {
+			add(e.pos, "feature `%s` has invalid expiry date: %v", name, err)
+			continue
+		}
+		if now.Before(expires) {
+			continue
+		}
+		sites := append(append([]token.Position(nil), u.registered[name]...), u.invoked[name]...)
+		if len(sites) == 0 {
+			add(e.pos, "feature `%s` is past its expiry date %s", name, e.date)
+		}
+		for _, pos := range sites {
+			add(pos, "feature `%s` is past its expiry date %s", name, e.date)
+		}
+	}
+	sort.Slice(stales, func(i, j int) bool {
+		a, b := stales[i], stales[j]
+		if a.pos.Filename != b.pos.Filename {
+			return a.pos.Filename < b.pos.Filename
+		}
+		if a.pos.Line != b.pos.Line {
+			return a.pos.Line < b.pos.Line
+		}
+		if a.pos.Column != b.pos.Column {
+			return a.pos.Column < b.pos.Column
+		}
+		return a.msg < b.msg
+	})
+	msgs := make([]string, len(stales))
+	for i, s := range stales {
+		msgs[i] = fmt.Sprintf("%v: %s", s.pos, s.msg)
+	}
+	return msgs
+}
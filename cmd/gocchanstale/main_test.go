@@ -0,0 +1,185 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func writeTestFiles(t *testing.T, files map[string]string) string {
+	dir := t.TempDir()
+	for name, src := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func Test_check(t *testing.T) {
+	dir := writeTestFiles(t, map[string]string{
+		"a.go": `package a
+
+import "github.com/naoina/gocchan"
+
+func init() {
+	gocchan.AddFeature("used", nil)
+	gocchan.AddFeature("unused", nil)
+	gocchan.AddFeature("expired", nil)
+}
+`,
+		"b/b.go": `package b
+
+import g "github.com/naoina/gocchan"
+
+func f() {
+	g.Invoke(nil, "used", "Func", nil)
+	g.ActiveIf("configured", nil)
+	g.IsActive("unregistered")
+	g.Invoke(nil, "expired", "Func", nil)
+	g.InvokeArgs(nil, "args", "Func", nil, nil)
+	g.InvokeTimeout(nil, "timeout", "Func", 0, nil)
+}
+`,
+		"b/b_test.go": `package b
+
+import "github.com/naoina/gocchan"
+
+func g() {
+	gocchan.Invoke(nil, "unused", "Func", nil)
+	gocchan.Invoke(nil, "unregistered2", "Func", nil)
+}
+`,
+		"testdata/c.go": `package c
+
+import "github.com/naoina/gocchan"
+
+func init() {
+	gocchan.AddFeature("testdata", nil)
+}
+`,
+		"config.json": `{
+	"features": {
+		"configured": {},
+		"expired": {"expires": "2014-01-01"},
+		"notyet": {"expires": "2015-01-01"}
+	}
+}
+`,
+	})
+	u := newUsage()
+	if err := u.scan(dir, false, nil); err != nil {
+		t.Fatal(err)
+	}
+	c := &config{}
+	if err := c.load(filepath.Join(dir, "config.json")); err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2014, 6, 1, 0, 0, 0, 0, time.UTC)
+	actual := check(u, c, now)
+	expected := []string{
+		filepath.Join(dir, "a.go") + ":7:2: feature `unused` is registered but never invoked",
+		filepath.Join(dir, "a.go") + ":8:2: feature `expired` is past its expiry date 2014-01-01",
+		filepath.Join(dir, "b", "b.go") + ":8:2: feature `unregistered` is invoked but never registered",
+		filepath.Join(dir, "b", "b.go") + ":9:2: feature `expired` is past its expiry date 2014-01-01",
+		filepath.Join(dir, "b", "b.go") + ":10:2: feature `args` is invoked but never registered",
+		filepath.Join(dir, "b", "b.go") + ":11:2: feature `timeout` is invoked but never registered",
+		filepath.Join(dir, "config.json") + ": feature `notyet` is registered but never invoked",
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Expect %q, but %q", expected, actual)
+	}
+
+	u = newUsage()
+	if err := u.scan(dir, true, nil); err != nil {
+		t.Fatal(err)
+	}
+	actual = check(u, &config{}, now)
+	expected = []string{
+		filepath.Join(dir, "b", "b.go") + ":7:2: feature `configured` is invoked but never registered",
+		filepath.Join(dir, "b", "b.go") + ":8:2: feature `unregistered` is invoked but never registered",
+		filepath.Join(dir, "b", "b.go") + ":10:2: feature `args` is invoked but never registered",
+		filepath.Join(dir, "b", "b.go") + ":11:2: feature `timeout` is invoked but never registered",
+		filepath.Join(dir, "b", "b_test.go") + ":7:2: feature `unregistered2` is invoked but never registered",
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Expect %q, but %q", expected, actual)
+	}
+}
+
+func Test_check_receivers(t *testing.T) {
+	dir := writeTestFiles(t, map[string]string{
+		"a.go": `package a
+
+import (
+	"time"
+
+	"github.com/naoina/gocchan"
+)
+
+func init() {
+	gocchan.As("ops", "launch").AddFeature("actor", nil)
+	gocchan.AddFeature("expired", nil, gocchan.Expires(time.Date(2014, time.January, 1, 0, 0, 0, 0, time.UTC)))
+	gocchan.AddFeature("notyet", nil, gocchan.Expires(time.Date(2015, 1, 1, 0, 0, 0, 0, time.UTC)))
+	gocchan.AddFeature("template", nil)
+	gocchan.AddFeature("rendered", nil)
+}
+`,
+		"a_test.go": `package a
+
+import "github.com/naoina/gocchan/gocchantest"
+
+func f(r *gocchantest.Registry) {
+	r.AddFeature("registry", nil)
+}
+`,
+		"b.go": `package b
+
+import "github.com/naoina/gocchan"
+
+func f() {
+	gocchan.Invoke(nil, "actor", "Func", nil)
+	gocchan.Invoke(nil, "expired", "Func", nil)
+	gocchan.Invoke(nil, "notyet", "Func", nil)
+	gocchan.Invoke(nil, "registry", "Func", nil)
+}
+`,
+		"views/index.tmpl": `{{if featureActive "template" .}}on{{end}}
+  {{with featureRender ` + "`rendered`" + ` "rendered/new" .}}{{.}}{{end}}
+{{featureActive "unregistered" .}}
+`,
+	})
+	u := newUsage()
+	if err := u.scan(dir, true, []string{".tmpl"}); err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2014, 6, 1, 0, 0, 0, 0, time.UTC)
+	actual := check(u, &config{}, now)
+	expected := []string{
+		filepath.Join(dir, "a.go") + ":11:2: feature `expired` is past its expiry date 2014-01-01",
+		filepath.Join(dir, "b.go") + ":7:2: feature `expired` is past its expiry date 2014-01-01",
+		filepath.Join(dir, "views", "index.tmpl") + ":3:3: feature `unregistered` is invoked but never registered",
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Expect %q, but %q", expected, actual)
+	}
+
+	c := &config{Features: map[string]*featureConfig{"notyet": {Expires: "2014-01-01", file: "config.json"}}}
+	actual = check(u, c, now)
+	expected = []string{
+		filepath.Join(dir, "a.go") + ":11:2: feature `expired` is past its expiry date 2014-01-01",
+		filepath.Join(dir, "a.go") + ":12:2: feature `notyet` is past its expiry date 2014-01-01",
+		filepath.Join(dir, "b.go") + ":7:2: feature `expired` is past its expiry date 2014-01-01",
+		filepath.Join(dir, "b.go") + ":8:2: feature `notyet` is past its expiry date 2014-01-01",
+		filepath.Join(dir, "views", "index.tmpl") + ":3:3: feature `unregistered` is invoked but never registered",
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Expect %q, but %q", expected, actual)
+	}
+}
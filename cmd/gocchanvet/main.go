@@ -0,0 +1,15 @@
+// Command gocchanvet validates the calls of gocchan.Invoke.
+//
+// It can be run standalone or by go vet:
+//
+//	go vet -vettool=$(which gocchanvet) ./...
+package main
+
+import (
+	"github.com/naoina/gocchan/invokecheck"
+	"golang.org/x/tools/go/analysis/singlechecker"
+)
+
+func main() {
+	singlechecker.Main(invokecheck.Analyzer)
+}
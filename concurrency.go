@@ -0,0 +1,52 @@
+package gocchan
+
+import (
+	"fmt"
+	"sync/atomic"
+)
+
+// MaxConcurrency returns an option that limits the number of concurrent executions of the methods of the feature to n.
+// When the number of executions in flight is at the limit, Invoke notifies EventFeatureConcurrencyLimited
+// and invokes the defaultFunc instead of the method. It isn't counted as a fault.
+// The method abandoned by the timeout is in flight until it returns. See Timeout.
+// If n isn't positive, it panic.
+func MaxConcurrency(n int) Option {
+	if n <= 0 {
+		panic(fmt.Sprintf("max concurrency must be positive: %d", n))
+	}
+	return func(st *status) {
+		st.maxConcurrency = int32(n)
+	}
+}
+
+// acquire increments the number of executions in flight and returns true if it is under the limit,
+// otherwise returns false.
+func (st *status) acquire() bool {
+	if st.maxConcurrency == 0 {
+		atomic.AddInt32(&st.inFlight, 1)
+		return true
+	}
+	for {
+		n := atomic.LoadInt32(&st.inFlight)
+		if n >= st.maxConcurrency {
+			return false
+		}
+		if atomic.CompareAndSwapInt32(&st.inFlight, n, n+1) {
+			return true
+		}
+	}
+}
+
+// release decrements the number of executions in flight.
+func (st *status) release() {
+	atomic.AddInt32(&st.inFlight, -1)
+}
+
+// InFlight returns the number of executions of the methods of the feature in flight.
+func InFlight(featureName string) int {
+	status := featureStatus[featureName]
+	if status == nil {
+		return 0
+	}
+	return int(atomic.LoadInt32(&status.inFlight))
+}
@@ -0,0 +1,107 @@
+package gocchan
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// blockingFeature is a feature whose method blocks until release is closed.
+type blockingFeature struct {
+	started chan struct{}
+	release chan struct{}
+}
+
+func (f *blockingFeature) ActiveIf(context interface{}, options ...interface{}) bool {
+	return true
+}
+
+func (f *blockingFeature) Func(context interface{}) {
+	f.started <- struct{}{}
+	<-f.release
+}
+
+func Test_MaxConcurrency(t *testing.T) {
+	func() {
+		defer func() {
+			if err := recover(); err == nil {
+				t.Errorf("panic doesn't occurred by max concurrency of zero")
+			}
+		}()
+		MaxConcurrency(0)
+	}()
+
+	defer Isolate()()
+	events := make(chanListener, 10)
+	AddEventListener(events)
+	feature := &blockingFeature{started: make(chan struct{}, 2), release: make(chan struct{})}
+	AddFeature("test", feature, MaxConcurrency(2))
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			Invoke("ctx", "test", "Func", func() {
+				t.Errorf("defaultFunc has been called under the limit")
+			})
+		}()
+	}
+	<-feature.started
+	<-feature.started
+	var actual interface{} = InFlight("test")
+	var expected interface{} = 2
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Expect %v, but %v", expected, actual)
+	}
+	called := false
+	Invoke("ctx", "test", "Func", func() {
+		called = true
+	})
+	if !called {
+		t.Errorf("defaultFunc hasn't been called at the limit")
+	}
+	close(feature.release)
+	wg.Wait()
+	actual = InFlight("test")
+	expected = 0
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Expect %v, but %v", expected, actual)
+	}
+	if !IsMethodActive("test", "Func") {
+		t.Errorf("method has been fault by the limit")
+	}
+	WaitNotify()
+	close(events)
+	var types []EventType
+	for event := range events {
+		if event.Type == EventFeatureConcurrencyLimited {
+			types = append(types, event.Type)
+		}
+	}
+	actual = types
+	expected = []EventType{EventFeatureConcurrencyLimited}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Expect %v, but %v", expected, actual)
+	}
+}
+
+func Test_MaxConcurrency_timeout(t *testing.T) {
+	defer Isolate()()
+	feature := &blockingFeature{started: make(chan struct{}, 1), release: make(chan struct{})}
+	AddFeature("test", feature, MaxConcurrency(1), Timeout(10*time.Millisecond), FaultThreshold(10))
+	Invoke("ctx", "test", "Func", nil)
+	if n := InFlight("test"); n != 1 {
+		t.Errorf("Expect abandoned method to be in flight, but %v", n)
+	}
+	close(feature.release)
+	for i := 0; InFlight("test") != 0; i++ {
+		if i > 100 {
+			t.Fatalf("abandoned method hasn't been released")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if n := InFlight("unknown"); n != 0 {
+		t.Errorf("Expect 0, but %v", n)
+	}
+}
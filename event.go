@@ -9,6 +9,19 @@ const (
 	EventFeatureWasFault
 	EventFeatureMethodInvalidNumberOfArguments
 	EventFeatureMethodSignatureMismatch
+
+	// EventFeatureInvoked and EventDefaultInvoked are notified only if enabled by NotifyInvocations.
+	EventFeatureInvoked
+	EventDefaultInvoked
+
+	EventFeatureExpired
+	EventFeatureExposure
+	EventFeatureShadowMismatch
+	EventFeatureTimeout
+	EventFeatureTripped
+	EventFeatureHalfOpen
+	EventFeatureRecovered
+	EventFeatureConcurrencyLimited
 )
 
 // String returns a name of event type.
@@ -24,6 +37,26 @@ func (typ EventType) String() string {
 		return "EventFeatureMethodInvalidNumberOfArguments"
 	case EventFeatureMethodSignatureMismatch:
 		return "EventFeatureMethodSignatureMismatch"
+	case EventFeatureInvoked:
+		return "EventFeatureInvoked"
+	case EventDefaultInvoked:
+		return "EventDefaultInvoked"
+	case EventFeatureExpired:
+		return "EventFeatureExpired"
+	case EventFeatureExposure:
+		return "EventFeatureExposure"
+	case EventFeatureShadowMismatch:
+		return "EventFeatureShadowMismatch"
+	case EventFeatureTimeout:
+		return "EventFeatureTimeout"
+	case EventFeatureTripped:
+		return "EventFeatureTripped"
+	case EventFeatureHalfOpen:
+		return "EventFeatureHalfOpen"
+	case EventFeatureRecovered:
+		return "EventFeatureRecovered"
+	case EventFeatureConcurrencyLimited:
+		return "EventFeatureConcurrencyLimited"
 	}
 	return "unknown"
 }
@@ -35,6 +68,12 @@ type Event struct {
 
 	// additional information of event.
 	Err interface{}
+
+	// name of feature which the event occurred in.
+	FeatureName string
+
+	// name of method which the event occurred in.
+	FuncName string
 }
 
 // NewEvent returns a new event.
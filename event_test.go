@@ -12,6 +12,16 @@ func Test_EventType_String(t *testing.T) {
 		"EventFeatureWasFault":                       EventFeatureWasFault,
 		"EventFeatureMethodInvalidNumberOfArguments": EventFeatureMethodInvalidNumberOfArguments,
 		"EventFeatureMethodSignatureMismatch":        EventFeatureMethodSignatureMismatch,
+		"EventFeatureInvoked":                        EventFeatureInvoked,
+		"EventDefaultInvoked":                        EventDefaultInvoked,
+		"EventFeatureExpired":                        EventFeatureExpired,
+		"EventFeatureExposure":                       EventFeatureExposure,
+		"EventFeatureShadowMismatch":                 EventFeatureShadowMismatch,
+		"EventFeatureTimeout":                        EventFeatureTimeout,
+		"EventFeatureTripped":                        EventFeatureTripped,
+		"EventFeatureHalfOpen":                       EventFeatureHalfOpen,
+		"EventFeatureRecovered":                      EventFeatureRecovered,
+		"EventFeatureConcurrencyLimited":             EventFeatureConcurrencyLimited,
 		"unknown": -1,
 	} {
 		actual := ev.String()
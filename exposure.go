@@ -0,0 +1,167 @@
+package gocchan
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// Exposure represents an exposure of the variant of experiment to the subject.
+// It is passed as Err of EventFeatureExposure.
+type Exposure struct {
+	// key of subject.
+	Subject string `json:"subject"`
+
+	// name of experiment.
+	FeatureName string `json:"feature"`
+
+	// name of variant.
+	Variant string `json:"variant"`
+
+	// time when the variant was exposed to the subject.
+	Time time.Time `json:"time"`
+}
+
+// exposures holds the last time of exposures to deduplicate.
+var exposures = &exposureLog{
+	window: 24 * time.Hour,
+	last:   make(map[Exposure]time.Time),
+}
+
+// exposureLog represents a log of exposures.
+type exposureLog struct {
+	// duration which the same exposures are deduplicated within.
+	window time.Duration
+
+	// last time of exposures by exposure without time.
+	last map[Exposure]time.Time
+
+	// number of exposures to sweep the expired exposures.
+	sweepAt int
+
+	mu sync.Mutex
+}
+
+// minSweepAt is the minimum number of exposures to sweep the expired exposures.
+const minSweepAt = 1024
+
+// SetExposureWindow sets the duration which the same exposures are deduplicated within.
+// EventFeatureExposure is emitted once per subject, experiment and variant within the duration.
+// If d is zero, it is emitted whenever the variant is invoked.
+// The default is 24 hours.
+func SetExposureWindow(d time.Duration) {
+	exposures.mu.Lock()
+	defer exposures.mu.Unlock()
+	exposures.window = d
+}
+
+// expose notifies EventFeatureExposure unless the same exposure has been notified within the window.
+func (l *exposureLog) expose(featureName, funcName, subject, variant string) {
+	t := now()
+	key := Exposure{Subject: subject, FeatureName: featureName, Variant: variant}
+	l.mu.Lock()
+	if last, exists := l.last[key]; exists && t.Sub(last) < l.window {
+		l.mu.Unlock()
+		return
+	}
+	if l.window > 0 {
+		l.last[key] = t
+		l.sweep(t)
+	}
+	l.mu.Unlock()
+	exposure := key
+	exposure.Time = t
+	notify(EventFeatureExposure, featureName, funcName, &exposure)
+}
+
+// sweep removes the exposures which are out of the window.
+func (l *exposureLog) sweep(t time.Time) {
+	if len(l.last) < l.sweepAt {
+		return
+	}
+	for key, last := range l.last {
+		if t.Sub(last) >= l.window {
+			delete(l.last, key)
+		}
+	}
+	if l.sweepAt = len(l.last) * 2; l.sweepAt < minSweepAt {
+		l.sweepAt = minSweepAt
+	}
+}
+
+// ExposureSink is an interface of sink of exposures.
+type ExposureSink interface {
+	// WriteExposure writes exposure.
+	WriteExposure(exposure *Exposure) error
+}
+
+// exposureListener is a listener which writes exposures to sink.
+type exposureListener struct {
+	sink ExposureSink
+}
+
+// NewExposureListener returns a new listener which writes the exposures of EventFeatureExposure to sink.
+// The errors of sink are logged by the standard logger.
+func NewExposureListener(sink ExposureSink) Listener {
+	return &exposureListener{sink: sink}
+}
+
+// Listen writes the exposure of event to sink.
+func (l *exposureListener) Listen(event *Event) {
+	if event.Type != EventFeatureExposure {
+		return
+	}
+	if err := l.sink.WriteExposure(event.Err.(*Exposure)); err != nil {
+		log.Printf("gocchan: failed to write exposure: %v", err)
+	}
+}
+
+// JSONLinesSink is a sink which writes exposures and audit entries in JSON Lines format.
+type JSONLinesSink struct {
+	w   io.Writer
+	enc *json.Encoder
+	mu  sync.Mutex
+}
+
+// NewJSONLinesSink returns a new JSONLinesSink which writes to w.
+func NewJSONLinesSink(w io.Writer) *JSONLinesSink {
+	return &JSONLinesSink{
+		w:   w,
+		enc: json.NewEncoder(w),
+	}
+}
+
+// OpenJSONLinesFile opens the file named name for appending and returns a new JSONLinesSink which writes to it.
+// If the file doesn't exist, it is created.
+func OpenJSONLinesFile(name string) (*JSONLinesSink, error) {
+	file, err := os.OpenFile(name, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return NewJSONLinesSink(file), nil
+}
+
+// WriteExposure writes exposure as a line of JSON.
+func (s *JSONLinesSink) WriteExposure(exposure *Exposure) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.enc.Encode(exposure)
+}
+
+// WriteAudit writes entry as a line of JSON.
+func (s *JSONLinesSink) WriteAudit(entry *AuditEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.enc.Encode(entry)
+}
+
+// Close closes the underlying writer if it is an io.Closer.
+func (s *JSONLinesSink) Close() error {
+	if c, ok := s.w.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
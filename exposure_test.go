@@ -0,0 +1,151 @@
+package gocchan
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func Test_Invoke_exposure(t *testing.T) {
+	defer Isolate()()
+	current := time.Date(2014, 1, 1, 0, 0, 0, 0, time.UTC)
+	SetClock(ClockFunc(func() time.Time { return current }))
+	SetExposureWindow(time.Hour)
+	events := make(chanListener, 100)
+	AddEventListener(events)
+	AddFeature("experiment", &TestExperiment{TestFeature{t, "test", true, nil, nil}}, Variants(Variant{"A", 1}))
+	AddFeature("inactive", &TestExperiment{TestFeature{t, "test", false, nil, nil}}, Variants(Variant{"A", 1}))
+	invoke := func() []*Exposure {
+		Invoke("subject1", "experiment", "Func", nil)
+		Invoke("subject1", "experiment", "Func", nil)
+		Invoke("subject2", "experiment", "Func", nil)
+		Invoke("subject1", "inactive", "Func", nil)
+		WaitNotify()
+		var exposures []*Exposure
+		for len(events) > 0 {
+			if event := <-events; event.Type == EventFeatureExposure {
+				exposures = append(exposures, event.Err.(*Exposure))
+			}
+		}
+		return exposures
+	}
+	actual := len(invoke())
+	expected := 2
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Expect %v, but %v", expected, actual)
+	}
+
+	current = current.Add(30 * time.Minute)
+	actual = len(invoke())
+	expected = 0
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Expect %v exposures within window, but %v", expected, actual)
+	}
+
+	current = current.Add(30 * time.Minute)
+	exposures := invoke()
+	actual = len(exposures)
+	expected = 2
+	if !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("Expect %v exposures after window, but %v", expected, actual)
+	}
+	for _, exposure := range exposures {
+		if exposure.FeatureName != "experiment" || exposure.Variant != "A" || !exposure.Time.Equal(current) {
+			t.Errorf("unexpected exposure: %v", exposure)
+		}
+	}
+
+	SetExposureWindow(0)
+	actual = len(invoke())
+	expected = 3
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Expect %v exposures without window, but %v", expected, actual)
+	}
+}
+
+// blockingExperiment is an experiment whose method of variant A blocks until release is closed.
+type blockingExperiment struct {
+	blockingFeature
+}
+
+func (f *blockingExperiment) FuncA(context interface{}) {
+	f.Func(context)
+}
+
+func Test_Invoke_exposure_concurrencyLimited(t *testing.T) {
+	defer Isolate()()
+	SetExposureWindow(0)
+	events := make(chanListener, 10)
+	AddEventListener(events)
+	feature := &blockingExperiment{blockingFeature{started: make(chan struct{}, 1), release: make(chan struct{})}}
+	AddFeature("experiment", feature, Variants(Variant{"A", 1}), MaxConcurrency(1))
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		Invoke("subject1", "experiment", "Func", nil)
+	}()
+	<-feature.started
+	Invoke("subject2", "experiment", "Func", nil)
+	close(feature.release)
+	<-done
+	WaitNotify()
+	close(events)
+	var actual []string
+	for event := range events {
+		if event.Type == EventFeatureExposure {
+			actual = append(actual, event.Err.(*Exposure).Subject)
+		}
+	}
+	expected := []string{"subject1"}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Expect %q, but %q", expected, actual)
+	}
+}
+
+func Test_NewExposureListener(t *testing.T) {
+	var buf bytes.Buffer
+	sink := NewJSONLinesSink(&buf)
+	listener := NewExposureListener(sink)
+	exposure := &Exposure{
+		Subject:     "subject1",
+		FeatureName: "experiment",
+		Variant:     "A",
+		Time:        time.Date(2014, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+	listener.Listen(&Event{Type: EventFeatureInvoked})
+	listener.Listen(&Event{Type: EventFeatureExposure, Err: exposure})
+	actual := buf.String()
+	expected := `{"subject":"subject1","feature":"experiment","variant":"A","time":"2014-01-01T00:00:00Z"}` + "\n"
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Expect %q, but %q", expected, actual)
+	}
+}
+
+func Test_OpenJSONLinesFile(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "exposures.jsonl")
+	for i := 0; i < 2; i++ {
+		sink, err := OpenJSONLinesFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := sink.WriteExposure(&Exposure{Subject: "subject1", FeatureName: "experiment", Variant: "A"}); err != nil {
+			t.Fatal(err)
+		}
+		if err := sink.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	b, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	actual := string(b)
+	line := `{"subject":"subject1","feature":"experiment","variant":"A","time":"0001-01-01T00:00:00Z"}` + "\n"
+	expected := line + line
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Expect %q, but %q", expected, actual)
+	}
+}
@@ -0,0 +1,234 @@
+package gocchan
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"time"
+)
+
+// ErrorPolicy represents how the error returned by a method of feature is treated.
+type ErrorPolicy int
+
+const (
+	// ErrorFallback invokes the defaultFunc only for the call which the error is returned.
+	ErrorFallback ErrorPolicy = iota
+
+	// ErrorFault invokes the defaultFunc and counts the error as a fault. See FaultThreshold.
+	ErrorFault
+)
+
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+// methodFault represents the fault state of a method of feature.
+type methodFault struct {
+	// number of consecutive faults.
+	count int
+
+	// Whether the method was fault.
+	fault bool
+
+	// time when the method was tripped by its latency. See Latency.
+	trippedAt time.Time
+
+	// Whether the trial call of the tripped method is running.
+	halfOpen bool
+}
+
+// FaultThreshold returns an option that sets the number of consecutive faults of a method until it is fault.
+// The fault of a method doesn't affect the other methods of the feature.
+// The default is 1. If n isn't positive, it panic.
+func FaultThreshold(n int) Option {
+	if n <= 0 {
+		panic(fmt.Sprintf("fault threshold must be positive: %d", n))
+	}
+	return func(st *status) {
+		st.faultThreshold = n
+	}
+}
+
+// OnError returns an option that sets how the error returned by a method of the feature is treated.
+// The method is regarded as returning an error if the type of its last result is error.
+// In either case, EventFeatureWasFault is notified with the error.
+// The default is ErrorFallback.
+func OnError(policy ErrorPolicy) Option {
+	return func(st *status) {
+		st.errorPolicy = policy
+	}
+}
+
+// resultError returns the error if the last value of out is a non-nil error, otherwise returns nil.
+func resultError(out []reflect.Value) error {
+	if len(out) == 0 {
+		return nil
+	}
+	v := out[len(out)-1]
+	if v.Type() != errorType || v.IsNil() {
+		return nil
+	}
+	return v.Interface().(error)
+}
+
+// recordError records the error returned by the method funcName according to the error policy of the feature.
+func (st *status) recordError(featureName, funcName string, err error) {
+	if st.errorPolicy == ErrorFault {
+		st.recordFault(featureName, funcName, err)
+		return
+	}
+	notify(EventFeatureWasFault, featureName, funcName, err)
+}
+
+// recordFault records a fault of the method funcName and notifies EventFeatureWasFault with err.
+func (st *status) recordFault(featureName, funcName string, err interface{}) {
+	st.countFault(featureName, funcName)
+	notify(EventFeatureWasFault, featureName, funcName, err)
+}
+
+// countFault counts a fault of the method funcName.
+// The method is fault when the number of consecutive faults reaches the threshold, and the state is saved.
+func (st *status) countFault(featureName, funcName string) {
+	st.mu.Lock()
+	if st.faults == nil {
+		st.faults = make(map[string]*methodFault)
+	}
+	f := st.faults[funcName]
+	if f == nil {
+		f = &methodFault{}
+		st.faults[funcName] = f
+	}
+	f.count++
+	threshold := st.faultThreshold
+	if threshold == 0 {
+		threshold = 1
+	}
+	faulted := !f.fault && f.count >= threshold
+	if faulted {
+		f.fault = true
+	}
+	st.mu.Unlock()
+	if faulted {
+		saveState()
+		a := Actor{Name: SystemActor, Reason: fmt.Sprintf("%d consecutive faults", threshold)}
+		audit(a, AuditFault, featureName, funcName, "active", "fault")
+	}
+}
+
+// recordSuccess resets the number of consecutive faults of the method funcName.
+func (st *status) recordSuccess(funcName string) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if f := st.faults[funcName]; f != nil {
+		f.count = 0
+	}
+}
+
+// isFault returns whether the feature was fault.
+func (st *status) isFault() bool {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return st.fault
+}
+
+// methodFault returns whether the method funcName or the feature was fault.
+func (st *status) methodFault(funcName string) bool {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if st.fault {
+		return true
+	}
+	f := st.faults[funcName]
+	return f != nil && f.fault
+}
+
+// faultedMethods returns the names of fault methods sorted by name.
+func (st *status) faultedMethods() []string {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return st.faultedMethodsLocked()
+}
+
+// faultedMethodsLocked is like faultedMethods but must be called with st.mu locked.
+func (st *status) faultedMethodsLocked() []string {
+	var names []string
+	for name, f := range st.faults {
+		if f.fault {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// faulted returns whether the feature or any of its methods is fault.
+func (st *status) faulted() bool {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if st.fault {
+		return true
+	}
+	for _, f := range st.faults {
+		if f.fault {
+			return true
+		}
+	}
+	return false
+}
+
+// IsMethodActive returns true if the feature and its method funcName aren't fault, otherwise returns false.
+func IsMethodActive(featureName, funcName string) bool {
+	status := featureStatus[featureName]
+	if status == nil {
+		return false
+	}
+	return !status.methodFault(funcName)
+}
+
+// FaultedMethods returns the names of fault methods of the feature sorted by name.
+func FaultedMethods(featureName string) []string {
+	status := featureStatus[featureName]
+	if status == nil {
+		return nil
+	}
+	return status.faultedMethods()
+}
+
+// ResetFault resets the fault state of the methods funcNames of the feature.
+// If funcNames aren't given, resets the fault state of the feature and its all methods.
+func ResetFault(featureName string, funcNames ...string) {
+	resetFault(Actor{}, featureName, funcNames...)
+}
+
+func resetFault(a Actor, featureName string, funcNames ...string) {
+	status := featureStatus[featureName]
+	if status == nil {
+		return
+	}
+	status.mu.Lock()
+	if len(funcNames) == 0 {
+		old := "active"
+		if status.fault || len(status.faultedMethodsLocked()) > 0 {
+			old = "fault"
+		}
+		status.fault = false
+		status.faults = nil
+		status.latencies = nil
+		status.mu.Unlock()
+		saveState()
+		audit(a, AuditResetFault, featureName, "", old, "active")
+		return
+	}
+	olds := make([]string, len(funcNames))
+	for i, name := range funcNames {
+		olds[i] = "active"
+		if f := status.faults[name]; f != nil && f.fault {
+			olds[i] = "fault"
+		}
+		delete(status.faults, name)
+		delete(status.latencies, name)
+	}
+	status.mu.Unlock()
+	saveState()
+	for i, name := range funcNames {
+		audit(a, AuditResetFault, featureName, name, olds[i], "active")
+	}
+}
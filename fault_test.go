@@ -0,0 +1,192 @@
+package gocchan
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+type flakyFeature struct {
+	fail bool
+}
+
+func (f *flakyFeature) ActiveIf(context interface{}, options ...interface{}) bool {
+	return true
+}
+
+func (f *flakyFeature) Func(context interface{}) {
+	if f.fail {
+		panic("flaky")
+	}
+}
+
+func Test_FaultThreshold(t *testing.T) {
+	func() {
+		defer func() {
+			if err := recover(); err == nil {
+				t.Errorf("panic doesn't occurred by threshold of zero")
+			}
+		}()
+		FaultThreshold(0)
+	}()
+
+	defer Isolate()()
+	feature := &flakyFeature{}
+	AddFeature("test", feature, FaultThreshold(2))
+	AddFeature("other", &TestFeature{t, "other", true, nil, nil})
+	invoke := func(fail bool) (called bool) {
+		feature.fail = fail
+		Invoke("ctx", "test", "Func", func() {
+			called = true
+		})
+		return called
+	}
+	invoke(true)
+	invoke(false)
+	invoke(true)
+	if !IsMethodActive("test", "Func") {
+		t.Errorf("method has been fault before consecutive faults reach threshold")
+	}
+	invoke(true)
+	if IsMethodActive("test", "Func") {
+		t.Errorf("method hasn't been fault by consecutive faults")
+	}
+	if !invoke(false) {
+		t.Errorf("defaultFunc hasn't been called after method is fault")
+	}
+	Invoke("ctx", "other", "FuncPanic", nil)
+	if !IsMethodActive("other", "Func1") {
+		t.Errorf("other method has been fault by fault of method")
+	}
+}
+
+func Test_FaultedMethods(t *testing.T) {
+	defer Isolate()()
+	AddFeature("test", &TestFeature{t, "test", true, nil, nil})
+	AddFeature("other", &TestFeature{t, "other", true, nil, nil})
+	Invoke("ctx", "test", "FuncPanic", nil)
+	Invoke("ctx", "test", "Func1", nil)
+	var actual interface{} = FaultedMethods("test")
+	var expected interface{} = []string{"FuncPanic"}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Expect %q, but %q", expected, actual)
+	}
+	actual = FaultedMethods("unknown")
+	expected = []string(nil)
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Expect %q, but %q", expected, actual)
+	}
+	for _, v := range []struct {
+		featureName, funcName string
+		expected              bool
+	}{
+		{"test", "FuncPanic", false},
+		{"test", "Func1", true},
+		{"other", "FuncPanic", true},
+		{"unknown", "Func1", false},
+	} {
+		actual := IsMethodActive(v.featureName, v.funcName)
+		if actual != v.expected {
+			t.Errorf("IsMethodActive(%q, %q) expect %v, but %v", v.featureName, v.funcName, v.expected, actual)
+		}
+	}
+	if !IsActive("test") {
+		t.Errorf("feature has been fault by fault of method")
+	}
+	infos := Features()
+	actual = []interface{}{infos[0].Name, infos[0].FaultedMethods, infos[1].Name, infos[1].FaultedMethods}
+	expected = []interface{}{"other", []string(nil), "test", []string{"FuncPanic"}}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Expect %q, but %q", expected, actual)
+	}
+}
+
+func Test_ResetFault(t *testing.T) {
+	defer Isolate()()
+	AddFeature("test", &TestFeature{t, "test", true, nil, nil})
+	Invoke("ctx", "test", "FuncPanic", nil)
+	Invoke("ctx", "test", "unknown", nil)
+	featureStatus["test"].faults["Other"] = &methodFault{count: 1, fault: true}
+	ResetFault("test", "FuncPanic")
+	actual := FaultedMethods("test")
+	expected := []string{"Other"}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Expect %q, but %q", expected, actual)
+	}
+
+	featureStatus["test"].fault = true
+	ResetFault("test")
+	if !IsActive("test") || len(FaultedMethods("test")) != 0 {
+		t.Errorf("fault state hasn't been reset")
+	}
+	ResetFault("unknown")
+}
+
+type errorFeature struct {
+	err error
+}
+
+func (f *errorFeature) ActiveIf(context interface{}, options ...interface{}) bool {
+	return true
+}
+
+func (f *errorFeature) Func(context interface{}) error {
+	return f.err
+}
+
+func (f *errorFeature) FuncValue(context interface{}) (int, error) {
+	return 1, f.err
+}
+
+func Test_OnError(t *testing.T) {
+	for _, v := range []struct {
+		policy   ErrorPolicy
+		expected bool
+	}{
+		{ErrorFallback, true},
+		{ErrorFault, false},
+	} {
+		func() {
+			defer Isolate()()
+			events := make(chanListener, 10)
+			AddEventListener(events)
+			feature := &errorFeature{}
+			AddFeature("test", feature, OnError(v.policy))
+			for _, funcName := range []string{"Func", "FuncValue"} {
+				var called bool
+				Invoke("ctx", "test", funcName, func() {
+					called = true
+				})
+				if called {
+					t.Errorf("defaultFunc has been called by nil error of %v", funcName)
+				}
+			}
+			feature.err = errors.New("testerr")
+			var called bool
+			Invoke("ctx", "test", "Func", func() {
+				called = true
+			})
+			if !called {
+				t.Errorf("defaultFunc hasn't been called by error with policy %v", v.policy)
+			}
+			var actual interface{} = IsMethodActive("test", "Func")
+			var expected interface{} = v.expected
+			if !reflect.DeepEqual(actual, expected) {
+				t.Errorf("Expect %v, but %v", expected, actual)
+			}
+			WaitNotify()
+			close(events)
+			var faults []interface{}
+			for event := range events {
+				if event.Type == EventFeatureWasFault {
+					faults = append(faults, event.Err)
+				}
+			}
+			actual = faults
+			expected = []interface{}{feature.err}
+			if !reflect.DeepEqual(actual, expected) {
+				t.Errorf("Expect %q, but %q", expected, actual)
+			}
+		}()
+	}
+}
@@ -4,15 +4,92 @@ import (
 	"errors"
 	"fmt"
 	"reflect"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
 var featureStatus = make(map[string]*status)
 
+// overrides holds forced activations by feature name.
+var (
+	overrides   = make(map[string]OverrideState)
+	overridesMu sync.RWMutex
+)
+
+// loadOverride returns the forced activation of feature.
+func loadOverride(featureName string) (OverrideState, bool) {
+	overridesMu.RLock()
+	defer overridesMu.RUnlock()
+	o, exists := overrides[featureName]
+	return o, exists
+}
+
+// storeOverride sets the forced activation of feature to o, and returns the previous one.
+func storeOverride(featureName string, o OverrideState) (old OverrideState, exists bool) {
+	overridesMu.Lock()
+	defer overridesMu.Unlock()
+	old, exists = overrides[featureName]
+	overrides[featureName] = o
+	return old, exists
+}
+
+// deleteOverride deletes the forced activation of feature, and returns the previous one.
+func deleteOverride(featureName string) (old OverrideState, exists bool) {
+	overridesMu.Lock()
+	defer overridesMu.Unlock()
+	old, exists = overrides[featureName]
+	delete(overrides, featureName)
+	return old, exists
+}
+
 type status struct {
 	feature Feature
 
 	// Whether the feature was fault.
 	fault bool
+
+	// fault state by method name.
+	faults map[string]*methodFault
+
+	// number of consecutive faults of a method until it is fault.
+	faultThreshold int
+
+	// how the error returned by a method is treated.
+	errorPolicy ErrorPolicy
+
+	// timeout of execution of a method.
+	timeout time.Duration
+
+	// policy of latency and the latency samples by method name.
+	latency   *LatencyPolicy
+	latencies map[string]*latencyWindow
+
+	// limit and number of executions of methods in flight.
+	maxConcurrency int32
+	inFlight       int32
+
+	// rate limiter of activations and the number of throttled activations.
+	limiter   *rateLimiter
+	throttled uint64
+
+	// cache of resolved methods.
+	methods   map[methodKey]*resolvedMethod
+	methodsMu sync.RWMutex
+
+	mu sync.Mutex
+
+	metadata Metadata
+
+	// variants of experiment.
+	variants []Variant
+
+	// names of prerequisite features.
+	requires []string
+
+	// schedules of activation.
+	schedules []Schedule
 }
 
 var (
@@ -28,22 +105,196 @@ type Feature interface {
 // ActiveIf returns true if ActiveIf of Feature returns true, otherwise returns false.
 func ActiveIf(featureName string, context interface{}, options ...interface{}) bool {
 	status := featureStatus[featureName]
-	if status == nil || status.fault {
+	if status == nil || status.isFault() {
 		return false
 	}
-	return status.feature.ActiveIf(context, options...)
+	return activeIf(status, featureName, context, options...)
+}
+
+// activeIf returns the forced activation if overridden, otherwise returns the result of ActiveIf of Feature
+// if the feature is scheduled, the all prerequisites are active and the subject is allocated to the feature in its groups.
+// If the assignment store is set and the subject can be identified by context,
+// the first result for the subject is stored and returned after that.
+// The active feature is inactive while it is throttled by the rate limit.
+func activeIf(status *status, featureName string, context interface{}, options ...interface{}) bool {
+	active, limited := decide(status, featureName, context, options...)
+	return active && (!limited || throttle(status, context))
+}
+
+// decide is like activeIf but doesn't take a token of the rate limit.
+// limited reports whether the activation is subject to the rate limit, that is, the feature isn't overridden.
+func decide(status *status, featureName string, context interface{}, options ...interface{}) (active, limited bool) {
+	if o, exists := loadOverride(featureName); exists {
+		return o.Active, false
+	}
+	return evaluate(status, featureName, context, true, options...), true
+}
+
+// peek is like activeIf but has no side effects.
+// It neither takes a token of the rate limit nor stores the assignment. It is used to check the prerequisites.
+func peek(status *status, featureName string, context interface{}, options ...interface{}) bool {
+	if o, exists := loadOverride(featureName); exists {
+		return o.Active
+	}
+	return evaluate(status, featureName, context, false, options...)
+}
+
+// evaluate returns the activation of the feature which isn't overridden. See activeIf.
+// If sticky is false, the first result for the subject isn't stored in the assignment store.
+func evaluate(status *status, featureName string, context interface{}, sticky bool, options ...interface{}) bool {
+	if !scheduled(status) || !prerequisitesActive(status, context, options...) || !allocated(featureName, context) {
+		return false
+	}
+	key, ok := subjectKey(context)
+	if !ok || assignments == nil {
+		return status.feature.ActiveIf(context, options...)
+	}
+	if a, ok := loadAssignment(featureName, key); ok {
+		return a.Active
+	}
+	a := Assignment{Active: status.feature.ActiveIf(context, options...)}
+	if !sticky {
+		return a.Active
+	}
+	if len(status.variants) > 0 {
+		a.Variant = variantOf(status, featureName, key)
+	}
+	storeAssignment(featureName, key, a)
+	return a.Active
+}
+
+// Override forces the activation of feature regardless of its ActiveIf.
+// The faulted feature is still inactive even if it was forced to be active.
+func Override(featureName string, active bool) {
+	override(Actor{}, featureName, active)
+}
+
+// OverrideWithReason is like Override but records the reason why the feature is forced.
+// The forced activation is persisted if the state store is set. See SetStateStore.
+func OverrideWithReason(featureName string, active bool, reason string) {
+	override(Actor{Reason: reason}, featureName, active)
+}
+
+func override(a Actor, featureName string, active bool) {
+	o := OverrideState{Active: active, Reason: a.Reason, Time: now()}
+	old, exists := storeOverride(featureName, o)
+	saveState()
+	audit(a, AuditOverride, featureName, "", activation(old, exists), activation(o, true))
+}
+
+// ClearOverride clears the forced activation of feature.
+func ClearOverride(featureName string) {
+	clearOverride(Actor{}, featureName)
+}
+
+func clearOverride(a Actor, featureName string) {
+	old, exists := deleteOverride(featureName)
+	saveState()
+	audit(a, AuditClearOverride, featureName, "", activation(old, exists), "")
 }
 
 // AddFeature adds feature with name.
-// If feature is nil, it panic.
-func AddFeature(name string, feature Feature) {
+// If feature is nil or the prerequisites make a cycle, it panic.
+func AddFeature(name string, feature Feature, opts ...Option) {
+	addFeature(Actor{}, name, feature, opts...)
+}
+
+func addFeature(a Actor, name string, feature Feature, opts ...Option) {
 	if feature == nil {
 		panic("Add Feature is nil")
 	}
-	featureStatus[name] = &status{
+	st := &status{
 		feature: feature,
 		fault:   false,
 	}
+	for _, opt := range opts {
+		opt(st)
+	}
+	checkCycle(name, st.requires)
+	old := ""
+	if featureStatus[name] != nil {
+		old = "added"
+	}
+	featureStatus[name] = st
+	audit(a, AuditAdd, name, "", old, "added")
+	if fs := pendingStates[name]; fs != nil {
+		st.restore(name, fs)
+		delete(pendingStates, name)
+	}
+}
+
+// RemoveFeature removes the feature. The forced activation of the feature is kept.
+// The features which require the removed feature are inactive. See Requires.
+func RemoveFeature(featureName string) {
+	removeFeature(Actor{}, featureName)
+}
+
+func removeFeature(a Actor, featureName string) {
+	if featureStatus[featureName] == nil {
+		return
+	}
+	delete(featureStatus, featureName)
+	saveState()
+	audit(a, AuditRemove, featureName, "", "added", "")
+}
+
+// FeatureInfo represents the information of added feature.
+type FeatureInfo struct {
+	Metadata
+
+	// name of feature.
+	Name string
+
+	// Whether the feature is active. See IsActive.
+	Active bool
+
+	// names of fault methods. See FaultedMethods.
+	FaultedMethods []string
+
+	// Whether the feature is expired.
+	Expired bool
+
+	// number of throttled activations. See Throttled.
+	Throttled uint64
+
+	// forced activation if overridden.
+	Override *OverrideState
+}
+
+// Features returns the information of all added features sorted by name.
+func Features() []*FeatureInfo {
+	infos := make([]*FeatureInfo, 0, len(featureStatus))
+	for name, st := range featureStatus {
+		info := &FeatureInfo{
+			Metadata:       st.metadata,
+			Name:           name,
+			Active:         !st.isFault(),
+			FaultedMethods: st.faultedMethods(),
+			Expired:        st.expired(),
+			Throttled:      atomic.LoadUint64(&st.throttled),
+		}
+		if o, exists := loadOverride(name); exists {
+			info.Override = &o
+		}
+		infos = append(infos, info)
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].Name < infos[j].Name
+	})
+	return infos
+}
+
+// expired returns whether the feature is expired.
+func (st *status) expired() bool {
+	return !st.metadata.Expires.IsZero() && !now().Before(st.metadata.Expires)
+}
+
+// notifyIfExpired notifies EventFeatureExpired if the feature is expired.
+func notifyIfExpired(st *status, featureName, funcName string) {
+	if st.expired() {
+		err := fmt.Errorf("feature has been expired at %v: `%s`", st.metadata.Expires, featureName)
+		notify(EventFeatureExpired, featureName, funcName, err)
+	}
 }
 
 // Invoke invokes function of added feature.
@@ -51,15 +302,34 @@ func AddFeature(name string, feature Feature) {
 // Will invoke the method named funcName if defined in Feature associated with featureName.
 // When featureName hasn't been added, funcName hasn't been defined, or any errors occurred,
 // will invoke the defaultFunc with given context if defaultFunc isn't nil.
-// Also if any errors occurred at least once, next invoking will always invoke the defaultFunc.
+// Also if any errors occurred in the method at least once, next invoking of the method will always invoke the defaultFunc.
+// The number of errors can be changed by FaultThreshold.
+// If the last result of the method is a non-nil error, will invoke the defaultFunc as well. See OnError.
+// If the method doesn't return within the timeout of the feature, will invoke the defaultFunc without waiting for it. See Timeout.
+// The method can also be fault by its latency. See Latency.
+// The number of concurrent executions can be limited by MaxConcurrency.
+// If the feature is an experiment, will invoke the method of the variant assigned to the subject. See Variants.
 func Invoke(context interface{}, featureName, funcName string, defaultFunc func(), options ...interface{}) {
+	invoke(context, featureName, funcName, nil, 0, defaultFunc, options...)
+}
+
+// InvokeArgs is like Invoke but invokes the method with context followed by args.
+// The method must take the parameters which args are assignable to after context.
+// If the method is variadic, the variadic arguments are given individually in args.
+func InvokeArgs(context interface{}, featureName, funcName string, args []interface{}, defaultFunc func(), options ...interface{}) {
+	invoke(context, featureName, funcName, args, 0, defaultFunc, options...)
+}
+
+// invoke invokes function of added feature with context and args as well as Invoke.
+// If timeout is positive, it is used instead of the timeout of the feature.
+func invoke(context interface{}, featureName, funcName string, args []interface{}, timeout time.Duration, defaultFunc func(), options ...interface{}) {
 	status := featureStatus[featureName]
 	defer func() {
 		if err := recover(); err != nil {
 			if err != ErrInvokeDefault {
-				status.fault = true
-				notifier.NotifyAll(NewEvent(EventFeatureWasFault, err))
+				status.recordFault(featureName, funcName, err)
 			}
+			notifyInvocation(EventDefaultInvoked, featureName, funcName)
 			if defaultFunc != nil {
 				defaultFunc()
 			}
@@ -67,48 +337,247 @@ func Invoke(context interface{}, featureName, funcName string, defaultFunc func(
 	}()
 	if status == nil {
 		err := fmt.Errorf("feature has not been added: `%s`", featureName)
-		event := NewEvent(EventFeatureHasNotBeenAdded, err)
-		notifier.NotifyAll(event)
+		notify(EventFeatureHasNotBeenAdded, featureName, funcName, err)
 		panic(ErrInvokeDefault)
 	}
-	if status.fault {
+	notifyIfExpired(status, featureName, funcName)
+	var subject, variant string
+	if len(status.variants) > 0 {
+		var ok bool
+		if variant, ok = assignVariant(status, featureName, context); !ok {
+			panic(ErrInvokeDefault)
+		}
+		subject, _ = subjectKey(context)
+		funcName += variant
+	}
+	// the trial of tripped method is started after the method is known to be called,
+	// so that the fallbacks by inactive subject or limits don't end the trial as a failure.
+	faulted := status.methodFault(funcName)
+	if faulted && !status.trialDue(funcName) {
 		panic(ErrInvokeDefault)
 	}
-	f := reflect.ValueOf(status.feature).MethodByName(funcName)
-	if !f.IsValid() {
-		err := fmt.Errorf("method is not found: `%s` in feature `%s`", funcName, featureName)
-		event := NewEvent(EventFeatureMethodMissing, err)
-		notifier.NotifyAll(event)
+	f, cvalue, avalues := method(status, featureName, funcName, context, args)
+	if timeout <= 0 {
+		timeout = status.timeout
+	}
+	var recovered bool
+	if admit(status, featureName, funcName, faulted, context, options...) {
+		defer func() {
+			status.endTrial(featureName, funcName, recovered)
+		}()
+	}
+	if len(status.variants) > 0 {
+		exposures.expose(featureName, funcName, subject, variant)
+	}
+	start := now()
+	out := call(status, featureName, funcName, timeout, f, cvalue, avalues)
+	elapsed := now().Sub(start)
+	if err := resultError(out); err != nil {
+		status.recordError(featureName, funcName, err)
 		panic(ErrInvokeDefault)
 	}
-	ftype := f.Type()
-	if ftype.NumIn() != 1 {
-		err := fmt.Errorf("number of arguments must be one: method `%s` in feature `%s`", funcName, featureName)
-		event := NewEvent(EventFeatureMethodInvalidNumberOfArguments, err)
-		notifier.NotifyAll(event)
+	status.recordSuccess(funcName)
+	recovered = status.recordLatency(featureName, funcName, elapsed)
+	notifyInvocation(EventFeatureInvoked, featureName, funcName)
+}
+
+// admit admits the call of the method funcName if the feature is active, and acquires the slot of its execution.
+// If faulted is true, it starts the trial of the method and returns true, and the caller must end the trial by endTrial.
+// If the call isn't admitted, it panics with ErrInvokeDefault.
+func admit(status *status, featureName, funcName string, faulted bool, context interface{}, options ...interface{}) (trial bool) {
+	active, limited := decide(status, featureName, context, options...)
+	if !active {
 		panic(ErrInvokeDefault)
 	}
-	cvalue := reflect.ValueOf(context)
-	if !cvalue.IsValid() {
-		cvalue = reflect.ValueOf(&context).Elem()
+	if !status.acquire() {
+		err := fmt.Errorf("number of concurrent executions has reached the limit %d: feature `%s`", status.maxConcurrency, featureName)
+		notify(EventFeatureConcurrencyLimited, featureName, funcName, err)
+		panic(ErrInvokeDefault)
 	}
-	if !cvalue.Type().AssignableTo(ftype.In(0)) {
-		err := fmt.Errorf("method signature mismatch: context is a type `%T`, but type `%s` is an argument type of the method `%s` in feature `%s`", context, ftype.In(0), funcName, featureName)
-		event := NewEvent(EventFeatureMethodSignatureMismatch, err)
-		notifier.NotifyAll(event)
+	// the token of the rate limit is taken after the slot is acquired,
+	// so that the fallbacks by the concurrency limit don't consume the tokens.
+	if limited && !throttle(status, context) {
+		status.release()
 		panic(ErrInvokeDefault)
 	}
-	if !status.feature.ActiveIf(context, options...) {
+	if faulted && !status.halfOpen(featureName, funcName) {
+		status.release()
 		panic(ErrInvokeDefault)
 	}
-	f.Call([]reflect.Value{cvalue})
+	return faulted
+}
+
+// interfaceType is the type of nil context.
+var interfaceType = reflect.TypeOf((*interface{})(nil)).Elem()
+
+// methodKey is a key of the cache of resolved methods.
+type methodKey struct {
+	funcName string
+
+	// type of context.
+	ctype reflect.Type
+
+	// number of arguments except context.
+	nargs int
+}
+
+// resolvedMethod represents a result of resolution of method.
+type resolvedMethod struct {
+	f reflect.Value
+
+	// event and error which are notified if the method can't be called.
+	typ EventType
+	err error
+}
+
+// method returns the method funcName of feature and the values of context and args to call it.
+// If the method can't be called with context and args, it notifies the event and panics with ErrInvokeDefault.
+// The resolution is cached per method, type of context and number of args in the status of feature,
+// so it is discarded when the feature is replaced by AddFeature.
+func method(status *status, featureName, funcName string, context interface{}, args []interface{}) (f, cvalue reflect.Value, avalues []reflect.Value) {
+	key := methodKey{funcName: funcName, ctype: reflect.TypeOf(context), nargs: len(args)}
+	status.methodsMu.RLock()
+	m := status.methods[key]
+	status.methodsMu.RUnlock()
+	if m == nil {
+		m = resolveMethod(status, featureName, funcName, context, len(args))
+		status.methodsMu.Lock()
+		if status.methods == nil {
+			status.methods = make(map[methodKey]*resolvedMethod)
+		}
+		status.methods[key] = m
+		status.methodsMu.Unlock()
+	}
+	if m.typ != 0 {
+		notify(m.typ, featureName, funcName, m.err)
+		panic(ErrInvokeDefault)
+	}
+	if cvalue = reflect.ValueOf(context); !cvalue.IsValid() {
+		cvalue = reflect.Zero(interfaceType)
+	}
+	if len(args) > 0 {
+		avalues = make([]reflect.Value, len(args))
+	}
+	for i, arg := range args {
+		ptype := paramType(m.f.Type(), i+1)
+		avalues[i] = reflect.ValueOf(arg)
+		switch {
+		case !avalues[i].IsValid() && canBeNil(ptype):
+			avalues[i] = reflect.Zero(ptype)
+		case !avalues[i].IsValid() || !avalues[i].Type().AssignableTo(ptype):
+			err := fmt.Errorf("method signature mismatch: argument %d is a type `%T`, but type `%s` is an argument type of the method `%s` in feature `%s`", i+2, arg, ptype, funcName, featureName)
+			notify(EventFeatureMethodSignatureMismatch, featureName, funcName, err)
+			panic(ErrInvokeDefault)
+		}
+	}
+	return m.f, cvalue, avalues
+}
+
+// resolveMethod resolves the method funcName of feature which is called with context and nargs arguments.
+func resolveMethod(status *status, featureName, funcName string, context interface{}, nargs int) *resolvedMethod {
+	f := reflect.ValueOf(status.feature).MethodByName(funcName)
+	if !f.IsValid() {
+		err := fmt.Errorf("method is not found: `%s` in feature `%s`", funcName, featureName)
+		return &resolvedMethod{typ: EventFeatureMethodMissing, err: err}
+	}
+	ftype := f.Type()
+	if n := ftype.NumIn(); ftype.IsVariadic() && nargs+1 < n-1 || !ftype.IsVariadic() && nargs+1 != n {
+		want := "one"
+		switch {
+		case ftype.IsVariadic():
+			want = fmt.Sprintf("at least %d", n-1)
+		case n != 1:
+			want = fmt.Sprint(n)
+		}
+		err := fmt.Errorf("number of arguments must be %s, but %d given: method `%s` in feature `%s`", want, nargs+1, funcName, featureName)
+		return &resolvedMethod{typ: EventFeatureMethodInvalidNumberOfArguments, err: err}
+	}
+	ctype := reflect.TypeOf(context)
+	if ctype == nil {
+		ctype = interfaceType
+	}
+	if ptype := paramType(ftype, 0); !ctype.AssignableTo(ptype) {
+		err := fmt.Errorf("method signature mismatch: context is a type `%T`, but type `%s` is an argument type of the method `%s` in feature `%s`", context, ptype, funcName, featureName)
+		return &resolvedMethod{typ: EventFeatureMethodSignatureMismatch, err: err}
+	}
+	return &resolvedMethod{f: f}
+}
+
+// paramType returns the type of i-th parameter of ftype.
+// The parameters after the variadic parameter have the element type of it.
+func paramType(ftype reflect.Type, i int) reflect.Type {
+	if n := ftype.NumIn(); ftype.IsVariadic() && i >= n-1 {
+		return ftype.In(n - 1).Elem()
+	}
+	return ftype.In(i)
+}
+
+// canBeNil returns whether the value of typ can be nil.
+func canBeNil(typ reflect.Type) bool {
+	switch typ.Kind() {
+	case reflect.Chan, reflect.Func, reflect.Interface, reflect.Map, reflect.Ptr, reflect.Slice:
+		return true
+	}
+	return false
+}
+
+// notify notifies the event which occurred in the method of feature to all listeners of global notifier.
+func notify(typ EventType, featureName, funcName string, err interface{}) {
+	notifier.mu.Lock()
+	n := len(notifier.listeners)
+	notifier.mu.Unlock()
+	if n == 0 {
+		return
+	}
+	event := NewEvent(typ, err)
+	event.FeatureName = featureName
+	event.FuncName = funcName
+	notifier.NotifyAll(event)
 }
 
 // IsActive returns true if feature is active, otherwise returns false.
+// The feature is active even if some of its methods are fault. See also IsMethodActive and FaultedMethods.
 func IsActive(featureName string) bool {
 	status := featureStatus[featureName]
 	if status == nil {
 		return false
 	}
-	return !status.fault
+	return !status.isFault()
+}
+
+// Isolate replaces the global features, groups, overrides, event listeners, notifications of invocations, exposures, assignment store, state store, audit log and clock with the initial ones,
+// and returns a function that restores the previous ones.
+// It is intended to be used by tests. See also the gocchantest package.
+func Isolate() (restore func()) {
+	overridesMu.Lock()
+	defer overridesMu.Unlock()
+	groupsMu.Lock()
+	defer groupsMu.Unlock()
+	prevFeatureStatus, prevGroups, prevOverrides := featureStatus, groups, overrides
+	prevStateStore, prevPendingStates, prevAudits := stateStore, pendingStates, audits
+	prevNotifier, prevExposures, prevAssignments, prevClock := notifier, exposures, assignments, clock
+	prevInvocationEvents := atomic.LoadInt32(&invocationEvents)
+	featureStatus = make(map[string]*status)
+	groups = make(map[string][]string)
+	overrides = make(map[string]OverrideState)
+	stateStore, pendingStates = nil, make(map[string]*FeatureState)
+	audits = &auditLog{size: defaultAuditLogSize}
+	notifier = &Notifier{}
+	atomic.StoreInt32(&invocationEvents, 0)
+	exposures = &exposureLog{
+		window: prevExposures.window,
+		last:   make(map[Exposure]time.Time),
+	}
+	assignments = nil
+	clock = systemClock{}
+	return func() {
+		overridesMu.Lock()
+		defer overridesMu.Unlock()
+		groupsMu.Lock()
+		defer groupsMu.Unlock()
+		featureStatus, groups, overrides = prevFeatureStatus, prevGroups, prevOverrides
+		stateStore, pendingStates, audits = prevStateStore, prevPendingStates, prevAudits
+		notifier, exposures, assignments, clock = prevNotifier, prevExposures, prevAssignments, prevClock
+		atomic.StoreInt32(&invocationEvents, prevInvocationEvents)
+	}
 }
@@ -3,6 +3,7 @@ package gocchan
 import (
 	"fmt"
 	"reflect"
+	"sync"
 	"testing"
 )
 
@@ -116,7 +117,7 @@ func Test_AddFeature(t *testing.T) {
 		actual := featureStatus[name]
 		expected := &status{feature: feature, fault: false}
 		if !reflect.DeepEqual(actual, expected) {
-			t.Errorf("Expect %q, but %q", expected, actual)
+			t.Errorf("Expect %v, but %v", expected, actual)
 		}
 	}()
 }
@@ -243,19 +244,23 @@ func Test_Invoke(t *testing.T) {
 		t.Errorf("Expect %q, but %q", expected, actual)
 	}
 
+	called = false
+	Invoke("testctx5", "testfeature", "FuncPanic", func() {
+		called = true
+	})
+	if !called {
+		t.Errorf("defaultFunc hasn't been called by after panic")
+	}
+
 	for _, fname := range []string{"Func1", "Func2"} {
-		called = false
 		Invoke("testctx5", "testfeature", fname, func() {
-			called = true
+			t.Errorf("defaultFunc has been called by after panic of other method: %v", fname)
 		})
-		if !called {
-			t.Errorf("defaultFunc hasn't been called by after panic: %v", fname)
-		}
-		actual = feature.calledBy
-		expected = []string{"Func1:testctx1", "Func1:testctx2", "Func2:testctx3"}
-		if !reflect.DeepEqual(actual, expected) {
-			t.Errorf("Expect %q, but %q", expected, actual)
-		}
+	}
+	actual = feature.calledBy
+	expected = []string{"Func1:testctx1", "Func1:testctx2", "Func2:testctx3", "Func1:testctx5", "Func2:testctx5"}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Expect %q, but %q", expected, actual)
 	}
 
 	func() {
@@ -349,7 +354,7 @@ func Test_IsActive(t *testing.T) {
 		actual := IsActive("testIsActive")
 		expected := true
 		if !reflect.DeepEqual(actual, expected) {
-			t.Errorf("Expect %q, but %q", expected, actual)
+			t.Errorf("Expect %v, but %v", expected, actual)
 		}
 	}()
 
@@ -364,7 +369,7 @@ func Test_IsActive(t *testing.T) {
 		actual := IsActive("testIsActive")
 		expected := false
 		if !reflect.DeepEqual(actual, expected) {
-			t.Errorf("Expect %q, but %q", expected, actual)
+			t.Errorf("Expect %v, but %v", expected, actual)
 		}
 	}()
 
@@ -383,7 +388,286 @@ func Test_IsActive(t *testing.T) {
 		actual := IsActive("testIsActive")
 		expected := false
 		if !reflect.DeepEqual(actual, expected) {
-			t.Errorf("Expect %q, but %q", expected, actual)
+			t.Errorf("Expect %v, but %v", expected, actual)
 		}
 	}()
 }
+
+func Test_Override(t *testing.T) {
+	defer Isolate()()
+	feature := &TestFeature{t, "test", false, nil, nil}
+	AddFeature("test", feature)
+	Override("test", true)
+	if !ActiveIf("test", "ctx1") {
+		t.Errorf("ActiveIf returns false by forced active")
+	}
+	Invoke("ctx2", "test", "Func1", func() {
+		t.Errorf("defaultFunc has been called by forced active")
+	})
+	actual := feature.calledBy
+	expected := []string{"Func1:ctx2"}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Expect %q, but %q", expected, actual)
+	}
+	if len(feature.activeIfCalledBy) != 0 {
+		t.Errorf("ActiveIf of Feature has been called by forced active")
+	}
+
+	feature.active = true
+	Override("test", false)
+	if ActiveIf("test", "ctx3") {
+		t.Errorf("ActiveIf returns true by forced inactive")
+	}
+
+	ClearOverride("test")
+	if !ActiveIf("test", "ctx4") {
+		t.Errorf("ActiveIf returns false after override is cleared")
+	}
+}
+
+func Test_Override_concurrent(t *testing.T) {
+	defer Isolate()()
+	AddFeature("test", &benchFeature{})
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func(active bool) {
+			defer wg.Done()
+			Override("test", active)
+			ClearOverride("test")
+		}(i%2 == 0)
+		go func() {
+			defer wg.Done()
+			Invoke("ctx", "test", "Func", nil)
+			Features()
+			Snapshot()
+		}()
+	}
+	wg.Wait()
+	if _, exists := loadOverride("test"); exists {
+		t.Errorf("override hasn't been cleared")
+	}
+}
+
+func Test_Isolate(t *testing.T) {
+	AddFeature("testIsolate", &TestFeature{t, "test", true, nil, nil})
+	defer delete(featureStatus, "testIsolate")
+	restore := Isolate()
+	if IsActive("testIsolate") {
+		t.Errorf("feature is visible after isolated")
+	}
+	AddFeature("testIsolated", &TestFeature{t, "test", true, nil, nil})
+	restore()
+	if !IsActive("testIsolate") {
+		t.Errorf("feature hasn't been restored")
+	}
+	if IsActive("testIsolated") {
+		t.Errorf("feature which has been added after isolated remains")
+	}
+}
+
+func Test_method_cache(t *testing.T) {
+	defer Isolate()()
+	events := make(chanListener, 10)
+	AddEventListener(events)
+	feature := &TestFeature{t, "test", true, nil, nil}
+	AddFeature("test", feature)
+	for i := 0; i < 2; i++ {
+		Invoke("ctx", "test", "Func1", nil)
+		Invoke(1, "test", "Func3", nil)
+	}
+	if !reflect.DeepEqual(feature.calledBy, []string{"Func1:ctx", "Func1:ctx"}) {
+		t.Errorf("Expect the cached method to be called, but %v", feature.calledBy)
+	}
+	Invoke("ctx", "test", "Func3", nil)
+
+	replaced := &TestFeature{t, "test", true, nil, nil}
+	AddFeature("test", replaced)
+	Invoke("ctx", "test", "Func1", nil)
+	if !reflect.DeepEqual(replaced.calledBy, []string{"Func1:ctx"}) {
+		t.Errorf("Expect the method of replaced feature to be called, but %v", replaced.calledBy)
+	}
+	WaitNotify()
+	close(events)
+	var actual []EventType
+	for event := range events {
+		if event.Type == EventFeatureMethodSignatureMismatch {
+			actual = append(actual, event.Type)
+		}
+	}
+	expected := []EventType{EventFeatureMethodSignatureMismatch, EventFeatureMethodSignatureMismatch}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Expect %v, but %v", expected, actual)
+	}
+}
+
+type benchFeature struct {
+	inactive bool
+}
+
+func (f *benchFeature) ActiveIf(context interface{}, options ...interface{}) bool {
+	return !f.inactive
+}
+
+func (f *benchFeature) Func(context string) {}
+
+func (f *benchFeature) FuncPanic(context string) {
+	panic("bench")
+}
+
+// benchInvokeCases returns the cases of benchmark of Invoke by name.
+func benchInvokeCases() map[string]func() {
+	AddFeature("active", &benchFeature{})
+	AddFeature("inactive", &benchFeature{inactive: true})
+	AddFeature("faulted", &benchFeature{})
+	Invoke("ctx", "faulted", "FuncPanic", nil)
+	defaultFunc := func() {}
+	return map[string]func(){
+		"active": func() {
+			Invoke("ctx", "active", "Func", defaultFunc)
+		},
+		"inactive": func() {
+			Invoke("ctx", "inactive", "Func", defaultFunc)
+		},
+		"missing": func() {
+			Invoke("ctx", "missing", "Func", defaultFunc)
+		},
+		"faulted": func() {
+			Invoke("ctx", "faulted", "FuncPanic", defaultFunc)
+		},
+	}
+}
+
+func BenchmarkInvoke(b *testing.B) {
+	defer Isolate()()
+	for name, invoke := range benchInvokeCases() {
+		b.Run(name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				invoke()
+			}
+		})
+	}
+}
+
+func BenchmarkInvoke_parallel(b *testing.B) {
+	defer Isolate()()
+	for name, invoke := range benchInvokeCases() {
+		b.Run(name, func(b *testing.B) {
+			b.ReportAllocs()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					invoke()
+				}
+			})
+		})
+	}
+}
+
+func BenchmarkActiveIf(b *testing.B) {
+	defer Isolate()()
+	AddFeature("active", &benchFeature{})
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		ActiveIf("active", "ctx")
+	}
+}
+
+func BenchmarkActiveIf_parallel(b *testing.B) {
+	defer Isolate()()
+	AddFeature("active", &benchFeature{})
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			ActiveIf("active", "ctx")
+		}
+	})
+}
+
+func BenchmarkMethod(b *testing.B) {
+	st := &status{feature: &benchFeature{}}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		method(st, "bench", "Func", "ctx", nil)
+	}
+}
+
+func BenchmarkResolveMethod(b *testing.B) {
+	st := &status{feature: &benchFeature{}}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		resolveMethod(st, "bench", "Func", "ctx", 0)
+	}
+}
+
+type argsFeature struct {
+	calledBy []string
+}
+
+func (f *argsFeature) ActiveIf(context interface{}, options ...interface{}) bool {
+	return true
+}
+
+func (f *argsFeature) Func(context string, n int, err error) {
+	f.calledBy = append(f.calledBy, fmt.Sprintf("Func:%v:%v:%v", context, n, err))
+}
+
+func (f *argsFeature) Variadic(context string, prefix string, values ...int) {
+	f.calledBy = append(f.calledBy, fmt.Sprintf("Variadic:%v:%v:%v", context, prefix, values))
+}
+
+func Test_InvokeArgs(t *testing.T) {
+	defer Isolate()()
+	events := make(chanListener, 100)
+	AddEventListener(events)
+	feature := &argsFeature{}
+	AddFeature("test", feature)
+	for _, v := range []struct {
+		funcName string
+		args     []interface{}
+		expected string
+		event    EventType
+		err      string
+	}{
+		{"Func", []interface{}{1, nil}, "Func:ctx:1:<nil>", 0, ""},
+		{"Variadic", []interface{}{"p"}, "Variadic:ctx:p:[]", 0, ""},
+		{"Variadic", []interface{}{"p", 1, 2}, "Variadic:ctx:p:[1 2]", 0, ""},
+		{"Func", []interface{}{1}, "", EventFeatureMethodInvalidNumberOfArguments, "number of arguments must be 3, but 2 given: method `Func` in feature `test`"},
+		{"Variadic", nil, "", EventFeatureMethodInvalidNumberOfArguments, "number of arguments must be at least 2, but 1 given: method `Variadic` in feature `test`"},
+		{"Func", []interface{}{"1", nil}, "", EventFeatureMethodSignatureMismatch, "method signature mismatch: argument 2 is a type `string`, but type `int` is an argument type of the method `Func` in feature `test`"},
+		{"Func", []interface{}{nil, nil}, "", EventFeatureMethodSignatureMismatch, "method signature mismatch: argument 2 is a type `<nil>`, but type `int` is an argument type of the method `Func` in feature `test`"},
+		{"Variadic", []interface{}{"p", 1, "2"}, "", EventFeatureMethodSignatureMismatch, "method signature mismatch: argument 4 is a type `string`, but type `int` is an argument type of the method `Variadic` in feature `test`"},
+	} {
+		feature.calledBy = nil
+		called := false
+		InvokeArgs("ctx", "test", v.funcName, v.args, func() {
+			called = true
+		})
+		WaitNotify()
+		if v.event == 0 {
+			if called || !reflect.DeepEqual(feature.calledBy, []string{v.expected}) {
+				t.Errorf("%v%v: Expect %q, but %q", v.funcName, v.args, v.expected, feature.calledBy)
+			}
+			for len(events) > 0 {
+				<-events
+			}
+			continue
+		}
+		if !called {
+			t.Errorf("%v%v: defaultFunc hasn't been called", v.funcName, v.args)
+		}
+		var actual []string
+		for len(events) > 0 {
+			if event := <-events; event.Type == v.event {
+				actual = append(actual, fmt.Sprint(event.Err))
+			}
+		}
+		expected := []string{v.err}
+		if !reflect.DeepEqual(actual, expected) {
+			t.Errorf("Expect %q, but %q", expected, actual)
+		}
+	}
+	if !IsMethodActive("test", "Func") {
+		t.Errorf("method has been fault by mismatched arguments")
+	}
+}
@@ -0,0 +1,139 @@
+// Package gocchantest provides utilities for testing of features.
+//
+// A Registry replaces the global features, overrides and event listeners of gocchan with empty ones
+// until the end of the test, so tests that use it must not be run in parallel.
+package gocchantest
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/naoina/gocchan"
+)
+
+// Invocation represents a result of Invoke.
+type Invocation struct {
+	// name of invoked feature.
+	FeatureName string
+
+	// name of invoked method.
+	FuncName string
+
+	// Whether the defaultFunc has been invoked instead of the method of feature.
+	Default bool
+}
+
+// Registry is an isolated registry of features for a test.
+// It records every emitted event.
+type Registry struct {
+	t        testing.TB
+	recorder *recorder
+}
+
+// recorder is a listener which records every event.
+type recorder struct {
+	events []*gocchan.Event
+	mu     sync.Mutex
+}
+
+// Listen implements the gocchan.Listener interface.
+func (r *recorder) Listen(event *gocchan.Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event)
+}
+
+// New returns a new isolated registry.
+// The previous global state of gocchan is restored when the test and all its subtests complete.
+func New(t testing.TB) *Registry {
+	r := &Registry{t: t, recorder: &recorder{}}
+	restore := gocchan.Isolate()
+	t.Cleanup(func() {
+		gocchan.WaitNotify()
+		restore()
+	})
+	gocchan.AddEventListener(r.recorder)
+	gocchan.NotifyInvocations(true)
+	return r
+}
+
+// AddFeature adds feature with name.
+func (r *Registry) AddFeature(name string, feature gocchan.Feature, opts ...gocchan.Option) {
+	gocchan.AddFeature(name, feature, opts...)
+}
+
+// ForceOn forces the feature to be active.
+func (r *Registry) ForceOn(featureName string) {
+	gocchan.Override(featureName, true)
+}
+
+// ForceOff forces the feature to be inactive.
+func (r *Registry) ForceOff(featureName string) {
+	gocchan.Override(featureName, false)
+}
+
+// Events returns the all events which have been emitted.
+// It blocks until the all notifications is finished.
+// Events are not ordered since they are notified concurrently.
+func (r *Registry) Events() []*gocchan.Event {
+	gocchan.WaitNotify()
+	r.recorder.mu.Lock()
+	defer r.recorder.mu.Unlock()
+	return append([]*gocchan.Event(nil), r.recorder.events...)
+}
+
+// Invocations returns the all results of Invoke.
+// Invocations are not ordered as well as Events.
+func (r *Registry) Invocations() []Invocation {
+	var invocations []Invocation
+	for _, event := range r.Events() {
+		switch event.Type {
+		case gocchan.EventFeatureInvoked, gocchan.EventDefaultInvoked:
+			invocations = append(invocations, Invocation{
+				FeatureName: event.FeatureName,
+				FuncName:    event.FuncName,
+				Default:     event.Type == gocchan.EventDefaultInvoked,
+			})
+		}
+	}
+	return invocations
+}
+
+// Count returns the number of invocations of funcName in feature.
+// If defaultFunc is true, counts the invocations which the defaultFunc has been invoked, otherwise the method of feature.
+func (r *Registry) Count(featureName, funcName string, defaultFunc bool) int {
+	n := 0
+	for _, inv := range r.Invocations() {
+		if inv.FeatureName == featureName && inv.FuncName == funcName && inv.Default == defaultFunc {
+			n++
+		}
+	}
+	return n
+}
+
+// AssertFeature reports an error if the method funcName of feature has never been invoked.
+func (r *Registry) AssertFeature(featureName, funcName string) {
+	r.t.Helper()
+	if r.Count(featureName, funcName, false) == 0 {
+		r.t.Errorf("method `%s` in feature `%s` has never been invoked", funcName, featureName)
+	}
+}
+
+// AssertDefault reports an error if the defaultFunc for funcName of feature has never been invoked.
+func (r *Registry) AssertDefault(featureName, funcName string) {
+	r.t.Helper()
+	if r.Count(featureName, funcName, true) == 0 {
+		r.t.Errorf("defaultFunc for method `%s` in feature `%s` has never been invoked", funcName, featureName)
+	}
+}
+
+// AssertEvent reports an error if the event of typ has never been emitted.
+func (r *Registry) AssertEvent(typ gocchan.EventType) {
+	r.t.Helper()
+	for _, event := range r.Events() {
+		if event.Type == typ {
+			return
+		}
+	}
+	r.t.Errorf("event %v has never been emitted", typ)
+}
@@ -0,0 +1,93 @@
+package gocchantest
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/naoina/gocchan"
+)
+
+type testFeature struct {
+	active bool
+	called []string
+}
+
+func (f *testFeature) ActiveIf(context interface{}, options ...interface{}) bool {
+	return f.active
+}
+
+func (f *testFeature) Func1(context interface{}) {
+	f.called = append(f.called, "Func1")
+}
+
+func (f *testFeature) FuncPanic(context interface{}) {
+	panic("expected panic")
+}
+
+func Test_New(t *testing.T) {
+	gocchan.AddFeature("outside", &testFeature{active: true})
+	t.Run("isolated", func(t *testing.T) {
+		New(t)
+		if gocchan.IsActive("outside") {
+			t.Errorf("feature which has been added outside of registry is visible")
+		}
+		gocchan.AddFeature("inside", &testFeature{active: true})
+	})
+	if !gocchan.IsActive("outside") {
+		t.Errorf("feature which has been added outside of registry hasn't been restored")
+	}
+	if gocchan.IsActive("inside") {
+		t.Errorf("feature which has been added inside of registry remains")
+	}
+}
+
+func Test_Registry_Force(t *testing.T) {
+	r := New(t)
+	feature := &testFeature{active: false}
+	r.AddFeature("test", feature)
+	r.ForceOn("test")
+	gocchan.Invoke("", "test", "Func1", nil)
+	actual := feature.called
+	expected := []string{"Func1"}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Expect %q, but %q", expected, actual)
+	}
+
+	feature.active = true
+	r.ForceOff("test")
+	called := false
+	gocchan.Invoke("", "test", "Func1", func() {
+		called = true
+	})
+	if !called {
+		t.Errorf("defaultFunc hasn't been called by forced off")
+	}
+}
+
+func Test_Registry_Invocations(t *testing.T) {
+	r := New(t)
+	r.AddFeature("test", &testFeature{active: true})
+	gocchan.Invoke("", "test", "Func1", nil)
+	gocchan.Invoke("", "test", "FuncPanic", nil)
+	gocchan.Invoke("", "unknown", "Func1", nil)
+	actual := r.Invocations()
+	sort.Slice(actual, func(i, j int) bool {
+		return actual[i].FeatureName+actual[i].FuncName < actual[j].FeatureName+actual[j].FuncName
+	})
+	expected := []Invocation{
+		{FeatureName: "test", FuncName: "Func1", Default: false},
+		{FeatureName: "test", FuncName: "FuncPanic", Default: true},
+		{FeatureName: "unknown", FuncName: "Func1", Default: true},
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Expect %v, but %v", expected, actual)
+	}
+	r.AssertFeature("test", "Func1")
+	r.AssertDefault("test", "FuncPanic")
+	r.AssertEvent(gocchan.EventFeatureWasFault)
+	r.AssertEvent(gocchan.EventFeatureHasNotBeenAdded)
+	if n := r.Count("test", "Func1", true); n != 0 {
+		t.Errorf("Expect 0, but %v", n)
+	}
+}
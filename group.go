@@ -0,0 +1,51 @@
+package gocchan
+
+import "sync"
+
+// groups holds the member feature names by group name.
+var (
+	groups   = make(map[string][]string)
+	groupsMu sync.RWMutex
+)
+
+// AddGroup adds the mutually exclusive group of features with name.
+// A subject can be in at most one feature of the group. The traffic is divided into the equal slices
+// for each member, and the subject is allocated to the slice deterministically.
+// The feature in the group is active only for the subjects who are allocated to its slice,
+// so the subject must be identified by context. See Subject.
+// If featureNames is empty, it panic.
+func AddGroup(name string, featureNames ...string) {
+	if len(featureNames) == 0 {
+		panic("Add Group has no features")
+	}
+	storeGroup(name, featureNames)
+}
+
+// storeGroup sets the members of group to featureNames. If featureNames is empty, the group is deleted.
+func storeGroup(name string, featureNames []string) {
+	groupsMu.Lock()
+	defer groupsMu.Unlock()
+	if len(featureNames) == 0 {
+		delete(groups, name)
+		return
+	}
+	groups[name] = append([]string(nil), featureNames...)
+}
+
+// allocated returns whether the subject identified by context is allocated to the feature in the all groups which it belongs to.
+func allocated(featureName string, context interface{}) bool {
+	groupsMu.RLock()
+	defer groupsMu.RUnlock()
+	for name, members := range groups {
+		for _, member := range members {
+			if member != featureName {
+				continue
+			}
+			key, ok := subjectKey(context)
+			if !ok || members[hash(name, key)%uint32(len(members))] != featureName {
+				return false
+			}
+		}
+	}
+	return true
+}
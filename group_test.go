@@ -0,0 +1,53 @@
+package gocchan
+
+import (
+	"fmt"
+	"testing"
+)
+
+func Test_AddGroup(t *testing.T) {
+	defer Isolate()()
+	func() {
+		defer func() {
+			if err := recover(); err == nil {
+				t.Errorf("panic doesn't occurred by empty group")
+			}
+		}()
+		AddGroup("empty")
+	}()
+
+	for _, name := range []string{"a", "b", "c", "outside"} {
+		AddFeature(name, &TestFeature{t, name, true, nil, nil})
+	}
+	AddGroup("group", "a", "b", "c")
+	counts := make(map[string]int)
+	for i := 0; i < 900; i++ {
+		subject := fmt.Sprintf("subject%d", i)
+		var active []string
+		for _, name := range []string{"a", "b", "c"} {
+			if ActiveIf(name, subject) {
+				active = append(active, name)
+			}
+		}
+		if len(active) != 1 {
+			t.Fatalf("%v is in %v features of group: %v", subject, len(active), active)
+		}
+		if !ActiveIf(active[0], subject) {
+			t.Fatalf("allocation of %v isn't deterministic", subject)
+		}
+		counts[active[0]]++
+		if !ActiveIf("outside", subject) {
+			t.Fatalf("feature outside of group is inactive for %v", subject)
+		}
+	}
+	for name, n := range counts {
+		if n < 200 || n > 400 {
+			t.Errorf("slices aren't equal: %v is %v of 900", name, n)
+		}
+	}
+	for _, name := range []string{"a", "b", "c"} {
+		if ActiveIf(name, 1) {
+			t.Errorf("feature %v in group is active for unidentified subject", name)
+		}
+	}
+}
@@ -0,0 +1,294 @@
+// Package invokecheck defines an Analyzer that validates the calls of gocchan.Invoke, gocchan.InvokeTimeout and gocchan.InvokeArgs.
+//
+// The feature names and the method names which are passed to them are resolved by the calls of gocchan.AddFeature,
+// gocchan.Actor.AddFeature and gocchantest.Registry.AddFeature in the analyzed package and its dependencies,
+// and the following are reported.
+//
+//   - feature name which hasn't been added.
+//   - method which isn't defined in the feature.
+//   - method which doesn't take the given number of arguments.
+//   - context or argument which isn't assignable to the parameter type of the method.
+//
+// The arguments of InvokeArgs are checked only when they're given as a composite literal or nil.
+// Only the names which are given as constants can be checked.
+// The methods of experiments, which are added with gocchan.Variants, aren't checked.
+// Note that the features which are added by the packages that depend on the analyzed package can't be resolved,
+// so the unknown feature names are reported only when the flag -unknown is set.
+package invokecheck
+
+import (
+	"fmt"
+	"go/ast"
+	"go/constant"
+	"go/types"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/inspect"
+	"golang.org/x/tools/go/ast/inspector"
+	"golang.org/x/tools/go/types/typeutil"
+)
+
+const gocchanPath = "github.com/naoina/gocchan"
+
+// Analyzer reports the invalid calls of gocchan.Invoke.
+var Analyzer = &analysis.Analyzer{
+	Name:      "invokecheck",
+	Doc:       "check for the calls of gocchan.Invoke with unknown feature, missing method or mismatched signature",
+	Requires:  []*analysis.Analyzer{inspect.Analyzer},
+	Run:       run,
+	FactTypes: []analysis.Fact{new(features)},
+}
+
+var reportUnknown bool
+
+func init() {
+	Analyzer.Flags.BoolVar(&reportUnknown, "unknown", false, "report the feature names which haven't been added")
+}
+
+// features is a fact of the features which have been added in the package.
+type features struct {
+	Features []feature
+}
+
+func (*features) AFact() {}
+
+func (f *features) String() string {
+	return fmt.Sprintf("features(%v)", f.Features)
+}
+
+// feature represents a feature which has been added by AddFeature.
+type feature struct {
+	// name of feature.
+	Name string
+
+	// package path and name of the type of feature.
+	// They're empty if the type can't be determined statically.
+	PkgPath  string
+	TypeName string
+
+	// Whether the feature is a pointer of the type.
+	Pointer bool
+}
+
+func run(pass *analysis.Pass) (interface{}, error) {
+	inspect := pass.ResultOf[inspect.Analyzer].(*inspector.Inspector)
+	known := make(map[string]types.Type)
+	for _, fact := range pass.AllPackageFacts() {
+		for _, f := range fact.Fact.(*features).Features {
+			known[f.Name] = lookupType(pass.Pkg, f)
+		}
+	}
+	var added []feature
+	var invokes []*ast.CallExpr
+	inspect.Preorder([]ast.Node{(*ast.CallExpr)(nil)}, func(n ast.Node) {
+		call := n.(*ast.CallExpr)
+		switch calleeName(pass, call) {
+		case "AddFeature":
+			name, ok := constString(pass, call.Args[0])
+			if !ok {
+				return
+			}
+			typ := pass.TypesInfo.TypeOf(call.Args[1])
+			if hasVariants(pass, call) {
+				typ = nil
+			}
+			known[name] = typ
+			added = append(added, newFeature(name, typ))
+		case "Invoke", "InvokeTimeout", "InvokeArgs":
+			invokes = append(invokes, call)
+		}
+	})
+	if len(added) > 0 {
+		pass.ExportPackageFact(&features{Features: added})
+	}
+	for _, call := range invokes {
+		checkInvoke(pass, call, known)
+	}
+	return nil, nil
+}
+
+// checkInvoke reports the problems of the call of Invoke, InvokeTimeout or InvokeArgs.
+func checkInvoke(pass *analysis.Pass, call *ast.CallExpr, known map[string]types.Type) {
+	featureName, ok := constString(pass, call.Args[1])
+	if !ok {
+		return
+	}
+	typ, exists := known[featureName]
+	if !exists {
+		if reportUnknown {
+			pass.Reportf(call.Args[1].Pos(), "feature has not been added: `%s`", featureName)
+		}
+		return
+	}
+	funcName, ok := constString(pass, call.Args[2])
+	if !ok || typ == nil || types.IsInterface(typ) {
+		return
+	}
+	sel := types.NewMethodSet(typ).Lookup(nil, funcName)
+	if sel == nil || !sel.Obj().Exported() {
+		pass.Reportf(call.Args[2].Pos(), "method is not found: `%s` in feature `%s`", funcName, featureName)
+		return
+	}
+	sig := sel.Obj().Type().(*types.Signature)
+	args, ok := invokeArgs(pass, call)
+	n := sig.Params().Len()
+	if !ok && n == 0 {
+		return
+	}
+	if ok && (sig.Variadic() && len(args)+1 < n-1 || !sig.Variadic() && len(args)+1 != n) {
+		want := "one"
+		switch {
+		case sig.Variadic():
+			want = fmt.Sprintf("at least %d", n-1)
+		case n != 1:
+			want = fmt.Sprint(n)
+		}
+		pass.Reportf(call.Args[2].Pos(), "number of arguments must be %s, but %d given: method `%s` in feature `%s`", want, len(args)+1, funcName, featureName)
+		return
+	}
+	if ctype := pass.TypesInfo.TypeOf(call.Args[0]); ctype != nil {
+		if basic, ok := ctype.(*types.Basic); ok && basic.Kind() == types.UntypedNil {
+			// nil context is passed as a value of interface{}.
+			ctype = types.NewInterfaceType(nil, nil)
+		} else if types.IsInterface(ctype) {
+			// the dynamic type of context can't be determined.
+			ctype = nil
+		}
+		if ctype != nil && !types.AssignableTo(types.Default(ctype), paramType(sig, 0)) {
+			pass.Reportf(call.Args[0].Pos(), "method signature mismatch: context is a type `%s`, but type `%s` is an argument type of the method `%s` in feature `%s`", types.Default(ctype), paramType(sig, 0), funcName, featureName)
+		}
+	}
+	for i, arg := range args {
+		atype := pass.TypesInfo.TypeOf(arg)
+		if atype == nil || types.IsInterface(atype) {
+			// the dynamic type of argument can't be determined.
+			continue
+		}
+		if ptype := paramType(sig, i+1); !types.AssignableTo(atype, ptype) {
+			pass.Reportf(arg.Pos(), "method signature mismatch: argument %d is a type `%s`, but type `%s` is an argument type of the method `%s` in feature `%s`", i+2, types.Default(atype), ptype, funcName, featureName)
+		}
+	}
+}
+
+// invokeArgs returns the arguments except context which are passed to the method by call.
+// It returns false if they can't be determined statically.
+func invokeArgs(pass *analysis.Pass, call *ast.CallExpr) ([]ast.Expr, bool) {
+	if calleeName(pass, call) != "InvokeArgs" {
+		return nil, true
+	}
+	if lit, ok := call.Args[3].(*ast.CompositeLit); ok {
+		return lit.Elts, true
+	}
+	if pass.TypesInfo.Types[call.Args[3]].IsNil() {
+		return nil, true
+	}
+	return nil, false
+}
+
+// paramType returns the type of i-th parameter of sig.
+// The parameters after the variadic parameter have the element type of it.
+func paramType(sig *types.Signature, i int) types.Type {
+	params := sig.Params()
+	if n := params.Len(); sig.Variadic() && i >= n-1 {
+		return params.At(n - 1).Type().(*types.Slice).Elem()
+	}
+	return params.At(i).Type()
+}
+
+// methods are the methods which are treated as the functions of gocchan package of the same name.
+// The key consists of the package path, the type name and the method name.
+var methods = map[string]bool{
+	gocchanPath + ".Actor.AddFeature":                true,
+	gocchanPath + "/gocchantest.Registry.AddFeature": true,
+}
+
+// calleeName returns the name of the function of gocchan package which is called by call.
+// It returns empty if call isn't a call of the function of gocchan package or the method in methods.
+func calleeName(pass *analysis.Pass, call *ast.CallExpr) string {
+	fn, ok := typeutil.Callee(pass.TypesInfo, call).(*types.Func)
+	if !ok || fn.Pkg() == nil {
+		return ""
+	}
+	recv := fn.Type().(*types.Signature).Recv()
+	if recv == nil {
+		if fn.Pkg().Path() != gocchanPath {
+			return ""
+		}
+		return fn.Name()
+	}
+	typ := recv.Type()
+	if ptr, ok := typ.(*types.Pointer); ok {
+		typ = ptr.Elem()
+	}
+	named, ok := typ.(*types.Named)
+	if !ok || !methods[fn.Pkg().Path()+"."+named.Obj().Name()+"."+fn.Name()] {
+		return ""
+	}
+	return fn.Name()
+}
+
+// hasVariants returns whether the call of AddFeature has the gocchan.Variants option.
+func hasVariants(pass *analysis.Pass, call *ast.CallExpr) bool {
+	for _, arg := range call.Args[2:] {
+		if opt, ok := arg.(*ast.CallExpr); ok && calleeName(pass, opt) == "Variants" {
+			return true
+		}
+	}
+	return false
+}
+
+// constString returns the value of expr if expr is a constant string.
+func constString(pass *analysis.Pass, expr ast.Expr) (string, bool) {
+	tv, ok := pass.TypesInfo.Types[expr]
+	if !ok || tv.Value == nil || tv.Value.Kind() != constant.String {
+		return "", false
+	}
+	return constant.StringVal(tv.Value), true
+}
+
+// newFeature returns a new feature with name and type.
+func newFeature(name string, typ types.Type) feature {
+	f := feature{Name: name}
+	if ptr, ok := typ.(*types.Pointer); ok {
+		f.Pointer = true
+		typ = ptr.Elem()
+	}
+	if named, ok := typ.(*types.Named); ok && named.Obj().Pkg() != nil {
+		f.PkgPath = named.Obj().Pkg().Path()
+		f.TypeName = named.Obj().Name()
+	}
+	return f
+}
+
+// lookupType returns the type of feature by searching pkg and its dependencies.
+// It returns nil if the type isn't found.
+func lookupType(pkg *types.Package, f feature) types.Type {
+	if f.TypeName == "" {
+		return nil
+	}
+	seen := make(map[*types.Package]bool)
+	var lookup func(pkg *types.Package) types.Type
+	lookup = func(pkg *types.Package) types.Type {
+		if seen[pkg] {
+			return nil
+		}
+		seen[pkg] = true
+		if pkg.Path() == f.PkgPath {
+			obj, ok := pkg.Scope().Lookup(f.TypeName).(*types.TypeName)
+			if !ok {
+				return nil
+			}
+			if f.Pointer {
+				return types.NewPointer(obj.Type())
+			}
+			return obj.Type()
+		}
+		for _, imp := range pkg.Imports() {
+			if typ := lookup(imp); typ != nil {
+				return typ
+			}
+		}
+		return nil
+	}
+	return lookup(pkg)
+}
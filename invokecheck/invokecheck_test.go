@@ -0,0 +1,19 @@
+package invokecheck
+
+import (
+	"testing"
+
+	"golang.org/x/tools/go/analysis/analysistest"
+)
+
+func Test_Analyzer(t *testing.T) {
+	if err := Analyzer.Flags.Set("unknown", "true"); err != nil {
+		t.Fatal(err)
+	}
+	defer Analyzer.Flags.Set("unknown", "false")
+	analysistest.Run(t, analysistest.TestData(), Analyzer, "a", "b")
+}
+
+func Test_Analyzer_methods(t *testing.T) {
+	analysistest.Run(t, analysistest.TestData(), Analyzer, "c")
+}
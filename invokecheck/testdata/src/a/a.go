@@ -0,0 +1,67 @@
+package a // want package:`features\(\[\{hello a HelloFeature true\} \{experiment   false\}\]\)`
+
+import (
+	"time"
+
+	"github.com/naoina/gocchan"
+)
+
+type HelloFeature struct{}
+
+func (f *HelloFeature) ActiveIf(context interface{}, options ...interface{}) bool {
+	return true
+}
+
+func (f *HelloFeature) Say(context interface{}) {}
+
+func (f *HelloFeature) SayString(context string) {}
+
+func (f *HelloFeature) SayTwo(context interface{}, other interface{}) {}
+
+func (f *HelloFeature) SayArgs(context string, n int, err error) {}
+
+func (f *HelloFeature) SayVariadic(context string, values ...int) {}
+
+func (f *HelloFeature) unexported(context interface{}) {}
+
+func init() {
+	gocchan.AddFeature("hello", &HelloFeature{})
+	gocchan.AddFeature("experiment", &HelloFeature{}, gocchan.Variants(gocchan.Variant{"A", 1}))
+}
+
+func invoke(name string, ctx interface{}) {
+	gocchan.Invoke("ctx", "hello", "Say", nil)
+	gocchan.Invoke(nil, "hello", "Say", nil)
+	gocchan.Invoke("ctx", "hello", "SayString", nil)
+	gocchan.Invoke(ctx, "hello", "SayString", nil)
+	gocchan.Invoke("ctx", name, "Say", nil)
+	gocchan.Invoke("ctx", "hello", name, nil)
+	gocchan.Invoke("ctx", "experiment", "Say", nil)
+	gocchan.Invoke("ctx", "unknown", "Say", nil)      // want "feature has not been added: `unknown`"
+	gocchan.Invoke("ctx", "hello", "Sya", nil)        // want "method is not found: `Sya` in feature `hello`"
+	gocchan.Invoke("ctx", "hello", "unexported", nil) // want "method is not found: `unexported` in feature `hello`"
+	gocchan.Invoke("ctx", "hello", "SayTwo", nil)     // want "number of arguments must be 2, but 1 given: method `SayTwo` in feature `hello`"
+	gocchan.Invoke(1, "hello", "SayString", nil)      // want "method signature mismatch: context is a type `int`, but type `string` is an argument type of the method `SayString` in feature `hello`"
+	gocchan.Invoke(nil, "hello", "SayString", nil)    // want "method signature mismatch: context is a type `interface{}`, but type `string` .*"
+	gocchan.Invoke("ctx", "hello", "SayVariadic", nil)
+}
+
+func invokeTimeout() {
+	gocchan.InvokeTimeout("ctx", "hello", "Say", time.Second, nil)
+	gocchan.InvokeTimeout("ctx", "unknown", "Say", time.Second, nil)  // want "feature has not been added: `unknown`"
+	gocchan.InvokeTimeout("ctx", "hello", "Sya", time.Second, nil)    // want "method is not found: `Sya` in feature `hello`"
+	gocchan.InvokeTimeout("ctx", "hello", "SayTwo", time.Second, nil) // want "number of arguments must be 2, but 1 given: method `SayTwo` in feature `hello`"
+	gocchan.InvokeTimeout(1, "hello", "SayString", time.Second, nil)  // want "method signature mismatch: context is a type `int`, but type `string` .*"
+}
+
+func invokeArgs(args []interface{}, v interface{}) {
+	gocchan.InvokeArgs("ctx", "hello", "SayArgs", []interface{}{1, nil}, nil)
+	gocchan.InvokeArgs("ctx", "hello", "SayArgs", []interface{}{v, v}, nil)
+	gocchan.InvokeArgs("ctx", "hello", "SayArgs", args, nil)
+	gocchan.InvokeArgs("ctx", "hello", "SayVariadic", []interface{}{1, 2}, nil)
+	gocchan.InvokeArgs("ctx", "hello", "Say", nil, nil)
+	gocchan.InvokeArgs("ctx", "hello", "SayArgs", []interface{}{1}, nil)          // want "number of arguments must be 3, but 2 given: method `SayArgs` in feature `hello`"
+	gocchan.InvokeArgs("ctx", "hello", "SayArgs", []interface{}{"1", nil}, nil)   // want "method signature mismatch: argument 2 is a type `string`, but type `int` is an argument type of the method `SayArgs` in feature `hello`"
+	gocchan.InvokeArgs("ctx", "hello", "SayVariadic", []interface{}{1, "2"}, nil) // want "method signature mismatch: argument 3 is a type `string`, but type `int` .*"
+	gocchan.InvokeArgs(1, "hello", "SayArgs", []interface{}{1, nil}, nil)         // want "method signature mismatch: context is a type `int`, but type `string` .*"
+}
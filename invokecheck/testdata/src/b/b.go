@@ -0,0 +1,13 @@
+package b
+
+import (
+	_ "a"
+
+	"github.com/naoina/gocchan"
+)
+
+func invoke() {
+	gocchan.Invoke("ctx", "hello", "Say", nil)
+	gocchan.Invoke("ctx", "hello", "Sya", nil)   // want "method is not found: `Sya` in feature `hello`"
+	gocchan.Invoke("ctx", "unknown", "Say", nil) // want "feature has not been added: `unknown`"
+}
@@ -0,0 +1,27 @@
+package c // want package:`features\(\[\{actor c HelloFeature true\} \{registry c HelloFeature false\}\]\)`
+
+import (
+	"github.com/naoina/gocchan"
+	"github.com/naoina/gocchan/gocchantest"
+)
+
+type HelloFeature struct{}
+
+func (f HelloFeature) ActiveIf(context interface{}, options ...interface{}) bool {
+	return true
+}
+
+func (f HelloFeature) Say(context interface{}) {}
+
+func init() {
+	gocchan.As("admin", "release").AddFeature("actor", &HelloFeature{})
+	gocchantest.New(nil).AddFeature("registry", HelloFeature{})
+}
+
+func invoke() {
+	gocchan.Invoke("ctx", "actor", "Say", nil)
+	gocchan.Invoke("ctx", "registry", "Say", nil)
+	gocchan.Invoke("ctx", "actor", "Sya", nil)    // want "method is not found: `Sya` in feature `actor`"
+	gocchan.Invoke("ctx", "registry", "Sya", nil) // want "method is not found: `Sya` in feature `registry`"
+	gocchan.Invoke("ctx", "unknown", "Say", nil)
+}
@@ -0,0 +1,33 @@
+package gocchan
+
+import "time"
+
+type Feature interface {
+	ActiveIf(context interface{}, options ...interface{}) bool
+}
+
+type Option func()
+
+type Variant struct {
+	Name   string
+	Weight int
+}
+
+func AddFeature(name string, feature Feature, opts ...Option) {}
+
+type Actor struct{}
+
+func As(name, reason string) Actor { return Actor{} }
+
+func (a Actor) AddFeature(name string, feature Feature, opts ...Option) {}
+
+func Variants(variants ...Variant) Option { return nil }
+
+func Invoke(context interface{}, featureName, funcName string, defaultFunc func(), options ...interface{}) {
+}
+
+func InvokeTimeout(context interface{}, featureName, funcName string, timeout time.Duration, defaultFunc func(), options ...interface{}) {
+}
+
+func InvokeArgs(context interface{}, featureName, funcName string, args []interface{}, defaultFunc func(), options ...interface{}) {
+}
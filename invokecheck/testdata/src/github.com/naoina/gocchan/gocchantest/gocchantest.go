@@ -0,0 +1,9 @@
+package gocchantest
+
+import "github.com/naoina/gocchan"
+
+type Registry struct{}
+
+func New(t interface{}) *Registry { return &Registry{} }
+
+func (r *Registry) AddFeature(name string, feature gocchan.Feature, opts ...gocchan.Option) {}
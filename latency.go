@@ -0,0 +1,210 @@
+package gocchan
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// LatencyPolicy represents a policy of automatic deactivation of the methods of feature by their latency.
+type LatencyPolicy struct {
+	// percentile of latency in (0, 1]. e.g. 0.99 for the 99th percentile.
+	Percentile float64
+
+	// upper limit of the percentile of latency.
+	Budget time.Duration
+
+	// length of sliding window of the latency samples.
+	// The window slides by a tenth of it.
+	Window time.Duration
+
+	// minimum number of samples in the window to evaluate the percentile.
+	// If it isn't positive, 1 is used.
+	MinSamples int
+
+	// duration from the trip until a trial call is allowed (half-open).
+	// If it isn't positive, the tripped method isn't recovered automatically. See ResetFault.
+	Cooldown time.Duration
+}
+
+// latencySlots is the number of slots of the sliding window of latencies.
+// The window slides by a slot, so the latencies are counted in a fixed memory.
+const latencySlots = 10
+
+// latencyWindow counts the latencies of a method in the sliding window.
+type latencyWindow [latencySlots]latencySlot
+
+// latencySlot counts the latencies in a slot of the window.
+type latencySlot struct {
+	// index of the slot from the Unix epoch.
+	index int64
+
+	// number of the latencies and the latencies over budget.
+	total, over int
+}
+
+// add counts elapsed at t, and returns the number of latencies and the latencies over budget in the window.
+func (w *latencyWindow) add(t time.Time, elapsed time.Duration, policy *LatencyPolicy) (total, over int) {
+	width := int64(policy.Window / latencySlots)
+	if width <= 0 {
+		width = 1
+	}
+	index := t.UnixNano() / width
+	s := &w[(index%latencySlots+latencySlots)%latencySlots]
+	if s.index != index {
+		*s = latencySlot{index: index}
+	}
+	s.total++
+	if elapsed > policy.Budget {
+		s.over++
+	}
+	for _, s := range w {
+		if s.index > index-latencySlots && s.index <= index {
+			total += s.total
+			over += s.over
+		}
+	}
+	return total, over
+}
+
+// overBudget returns whether the p-th percentile of latencies by the nearest-rank method is over budget,
+// where total is the number of latencies and over is the number of latencies over budget.
+func overBudget(total, over int, p float64) bool {
+	rank := int(math.Ceil(p * float64(total)))
+	if rank < 1 {
+		rank = 1
+	}
+	return total-over < rank
+}
+
+// Latency returns an option that trips the method of the feature to fault when the percentile of its latency
+// in the sliding window exceeds the budget, and notifies EventFeatureTripped.
+// After the cooldown, a single call of the tripped method is allowed as a trial (half-open) and EventFeatureHalfOpen is notified.
+// If the trial is finished within the budget, the method is recovered and EventFeatureRecovered is notified,
+// otherwise it is tripped again.
+// The latency is measured with the global clock. See SetClock.
+// If Percentile, Budget or Window is invalid, it panic.
+func Latency(policy LatencyPolicy) Option {
+	if policy.Percentile <= 0 || policy.Percentile > 1 {
+		panic(fmt.Sprintf("percentile must be in (0, 1]: %v", policy.Percentile))
+	}
+	if policy.Budget <= 0 || policy.Window <= 0 {
+		panic(fmt.Sprintf("budget and window must be positive: %v, %v", policy.Budget, policy.Window))
+	}
+	if policy.MinSamples <= 0 {
+		policy.MinSamples = 1
+	}
+	return func(st *status) {
+		st.latency = &policy
+	}
+}
+
+// recordLatency records the latency of the method funcName, and trips the method if the percentile of latency exceeds the budget.
+// It returns whether elapsed is within the budget.
+func (st *status) recordLatency(featureName, funcName string, elapsed time.Duration) bool {
+	policy := st.latency
+	if policy == nil {
+		return true
+	}
+	t := now()
+	st.mu.Lock()
+	if st.latencies == nil {
+		st.latencies = make(map[string]*latencyWindow)
+	}
+	w := st.latencies[funcName]
+	if w == nil {
+		w = &latencyWindow{}
+		st.latencies[funcName] = w
+	}
+	total, over := w.add(t, elapsed, policy)
+	tripped := false
+	if f := st.faults[funcName]; total >= policy.MinSamples && (f == nil || !f.fault) && overBudget(total, over, policy.Percentile) {
+		st.trip(funcName, t)
+		tripped = true
+	}
+	st.mu.Unlock()
+	if tripped {
+		saveState()
+		err := fmt.Errorf("latency percentile %v is over budget %v by %d of %d samples: `%s` in feature `%s`", policy.Percentile, policy.Budget, over, total, funcName, featureName)
+		audit(Actor{Name: SystemActor, Reason: err.Error()}, AuditFault, featureName, funcName, "active", "fault")
+		notify(EventFeatureTripped, featureName, funcName, err)
+	}
+	return elapsed <= policy.Budget
+}
+
+// trip makes the method funcName fault at t and discards its latency samples.
+// It must be called with st.mu locked.
+func (st *status) trip(funcName string, t time.Time) {
+	if st.faults == nil {
+		st.faults = make(map[string]*methodFault)
+	}
+	f := st.faults[funcName]
+	if f == nil {
+		f = &methodFault{}
+		st.faults[funcName] = f
+	}
+	f.fault = true
+	f.halfOpen = false
+	f.trippedAt = t
+	delete(st.latencies, funcName)
+}
+
+// trialDue returns true if the method funcName has been tripped by its latency and the cooldown has passed,
+// and no trial of it is in progress. It doesn't start a trial. See halfOpen.
+func (st *status) trialDue(funcName string) bool {
+	if st.latency == nil || st.latency.Cooldown <= 0 {
+		return false
+	}
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return st.trialDueLocked(funcName)
+}
+
+// trialDueLocked is the same as trialDue, but it must be called with st.mu locked.
+func (st *status) trialDueLocked(funcName string) bool {
+	f := st.faults[funcName]
+	return !st.fault && f != nil && f.fault && !f.halfOpen && !f.trippedAt.IsZero() && now().Sub(f.trippedAt) >= st.latency.Cooldown
+}
+
+// halfOpen starts a trial of the method funcName and returns true if the trial is due.
+// It allows only a single trial at once, and notifies EventFeatureHalfOpen.
+// It must be called only when the method is going to be called, since the trial ends with a verdict by endTrial.
+func (st *status) halfOpen(featureName, funcName string) bool {
+	policy := st.latency
+	if policy == nil || policy.Cooldown <= 0 {
+		return false
+	}
+	st.mu.Lock()
+	if !st.trialDueLocked(funcName) {
+		st.mu.Unlock()
+		return false
+	}
+	st.faults[funcName].halfOpen = true
+	st.mu.Unlock()
+	err := fmt.Errorf("method is on trial after cooldown %v: `%s` in feature `%s`", policy.Cooldown, funcName, featureName)
+	notify(EventFeatureHalfOpen, featureName, funcName, err)
+	return true
+}
+
+// endTrial finishes the trial of the method funcName.
+// If ok is true, the method is recovered and EventFeatureRecovered is notified,
+// otherwise the method is tripped again and EventFeatureTripped is notified.
+func (st *status) endTrial(featureName, funcName string, ok bool) {
+	st.mu.Lock()
+	if ok {
+		delete(st.faults, funcName)
+		delete(st.latencies, funcName)
+	} else {
+		st.trip(funcName, now())
+	}
+	st.mu.Unlock()
+	saveState()
+	if ok {
+		audit(Actor{Name: SystemActor, Reason: "trial succeeded"}, AuditResetFault, featureName, funcName, "half-open", "active")
+		notify(EventFeatureRecovered, featureName, funcName, nil)
+		return
+	}
+	err := fmt.Errorf("trial has been failed: `%s` in feature `%s`", funcName, featureName)
+	audit(Actor{Name: SystemActor, Reason: err.Error()}, AuditFault, featureName, funcName, "half-open", "fault")
+	notify(EventFeatureTripped, featureName, funcName, err)
+}
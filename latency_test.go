@@ -0,0 +1,188 @@
+package gocchan
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+// latencyFeature is a feature whose method advances the clock by the given latency.
+type latencyFeature struct {
+	now *time.Time
+}
+
+func (f *latencyFeature) ActiveIf(context interface{}, options ...interface{}) bool {
+	return true
+}
+
+func (f *latencyFeature) Func(latency time.Duration) {
+	*f.now = f.now.Add(latency)
+}
+
+func Test_Latency_invalid(t *testing.T) {
+	for _, policy := range []LatencyPolicy{
+		{Percentile: 0, Budget: time.Second, Window: time.Minute},
+		{Percentile: 1.5, Budget: time.Second, Window: time.Minute},
+		{Percentile: 0.9, Budget: 0, Window: time.Minute},
+		{Percentile: 0.9, Budget: time.Second, Window: 0},
+	} {
+		func() {
+			defer func() {
+				if err := recover(); err == nil {
+					t.Errorf("panic doesn't occurred by %v", policy)
+				}
+			}()
+			Latency(policy)
+		}()
+	}
+}
+
+func Test_Latency(t *testing.T) {
+	defer Isolate()()
+	current := time.Date(2014, 6, 1, 0, 0, 0, 0, time.UTC)
+	SetClock(ClockFunc(func() time.Time { return current }))
+	events := make(chanListener, 100)
+	AddEventListener(events)
+	AddFeature("test", &latencyFeature{&current}, Latency(LatencyPolicy{
+		Percentile: 0.5,
+		Budget:     100 * time.Millisecond,
+		Window:     time.Minute,
+		MinSamples: 3,
+		Cooldown:   time.Minute,
+	}))
+	invoke := func(latency time.Duration) (called bool) {
+		Invoke(latency, "test", "Func", func() {
+			called = true
+		})
+		return called
+	}
+	invoke(time.Second)
+	invoke(time.Second)
+	if !IsMethodActive("test", "Func") {
+		t.Errorf("method has been tripped before samples reach minimum")
+	}
+	invoke(10 * time.Millisecond)
+	if IsMethodActive("test", "Func") {
+		t.Errorf("method hasn't been tripped by latency over budget")
+	}
+	if !invoke(0) {
+		t.Errorf("defaultFunc hasn't been called by tripped method")
+	}
+
+	// trial is failed.
+	current = current.Add(time.Minute)
+	if invoke(time.Second) {
+		t.Errorf("defaultFunc has been called by trial")
+	}
+	if IsMethodActive("test", "Func") {
+		t.Errorf("method has been recovered by failed trial")
+	}
+	if !invoke(0) {
+		t.Errorf("defaultFunc hasn't been called during cooldown")
+	}
+
+	// trial isn't started by the fallback before calling the method.
+	current = current.Add(time.Minute)
+	Override("test", false)
+	if !invoke(0) {
+		t.Errorf("defaultFunc hasn't been called by inactive feature")
+	}
+	ClearOverride("test")
+
+	// trial is succeeded.
+	invoke(10 * time.Millisecond)
+	if !IsMethodActive("test", "Func") {
+		t.Errorf("method hasn't been recovered by succeeded trial")
+	}
+	WaitNotify()
+	close(events)
+	var actual []EventType
+	for event := range events {
+		actual = append(actual, event.Type)
+	}
+	sort.Slice(actual, func(i, j int) bool {
+		return actual[i] < actual[j]
+	})
+	expected := []EventType{EventFeatureTripped, EventFeatureTripped, EventFeatureHalfOpen, EventFeatureHalfOpen, EventFeatureRecovered}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Expect %v, but %v", expected, actual)
+	}
+}
+
+func Test_Latency_window(t *testing.T) {
+	defer Isolate()()
+	current := time.Date(2014, 6, 1, 0, 0, 0, 0, time.UTC)
+	SetClock(ClockFunc(func() time.Time { return current }))
+	AddFeature("test", &latencyFeature{&current}, Latency(LatencyPolicy{
+		Percentile: 1,
+		Budget:     100 * time.Millisecond,
+		Window:     time.Minute,
+		MinSamples: 2,
+	}))
+	Invoke(time.Second, "test", "Func", nil)
+	current = current.Add(time.Minute)
+	Invoke(10*time.Millisecond, "test", "Func", nil)
+	if !IsMethodActive("test", "Func") {
+		t.Errorf("method has been tripped by samples out of window")
+	}
+	Invoke(time.Second, "test", "Func", nil)
+	if IsMethodActive("test", "Func") {
+		t.Errorf("method hasn't been tripped by latency over budget")
+	}
+	current = current.Add(time.Hour)
+	Invoke(0, "test", "Func", nil)
+	if IsMethodActive("test", "Func") {
+		t.Errorf("method has been recovered without cooldown")
+	}
+	ResetFault("test", "Func")
+	if !IsMethodActive("test", "Func") {
+		t.Errorf("method hasn't been reset")
+	}
+}
+
+func Test_latencyWindow_add(t *testing.T) {
+	policy := &LatencyPolicy{Budget: 10, Window: time.Minute}
+	start := time.Date(2014, 6, 1, 0, 0, 0, 0, time.UTC)
+	var w latencyWindow
+	for _, v := range []struct {
+		t        time.Duration
+		elapsed  time.Duration
+		expected []int
+	}{
+		{0, 10, []int{1, 0}},
+		{time.Second, 11, []int{2, 1}},
+		{30 * time.Second, 20, []int{3, 2}},
+		{time.Minute, 1, []int{2, 1}},
+		{time.Minute + 30*time.Second, 1, []int{2, 0}},
+		{10 * time.Minute, 1, []int{1, 0}},
+	} {
+		total, over := w.add(start.Add(v.t), v.elapsed, policy)
+		actual := []int{total, over}
+		if !reflect.DeepEqual(actual, v.expected) {
+			t.Errorf("add at %v: Expect %v, but %v", v.t, v.expected, actual)
+		}
+	}
+}
+
+func Test_overBudget(t *testing.T) {
+	for _, v := range []struct {
+		total, over int
+		p           float64
+		expected    bool
+	}{
+		{5, 0, 1, false},
+		{5, 1, 1, true},
+		{5, 1, 0.8, false},
+		{5, 2, 0.8, true},
+		{5, 2, 0.5, false},
+		{5, 3, 0.5, true},
+		{5, 4, 0.01, false},
+		{5, 5, 0.01, true},
+	} {
+		actual := overBudget(v.total, v.over, v.p)
+		if !reflect.DeepEqual(actual, v.expected) {
+			t.Errorf("overBudget(%v, %v, %v): Expect %v, but %v", v.total, v.over, v.p, v.expected, actual)
+		}
+	}
+}
@@ -1,6 +1,10 @@
 package gocchan
 
-import "sync"
+import (
+	"log"
+	"sync"
+	"sync/atomic"
+)
 
 // Notifier represents a notifier of any event.
 type Notifier struct {
@@ -14,6 +18,27 @@ func WaitNotify() {
 	notifier.Wait()
 }
 
+// invocationEvents is non-zero if EventFeatureInvoked and EventDefaultInvoked are notified.
+var invocationEvents int32
+
+// NotifyInvocations enables or disables the notifications of EventFeatureInvoked and EventDefaultInvoked,
+// which are notified on every invocation. They are disabled by default.
+// It is intended to be used by tests. See also the gocchantest package.
+func NotifyInvocations(enabled bool) {
+	var v int32
+	if enabled {
+		v = 1
+	}
+	atomic.StoreInt32(&invocationEvents, v)
+}
+
+// notifyInvocation notifies the event of invocation if it is enabled. See NotifyInvocations.
+func notifyInvocation(typ EventType, featureName, funcName string) {
+	if atomic.LoadInt32(&invocationEvents) != 0 {
+		notify(typ, featureName, funcName, nil)
+	}
+}
+
 // NotifyAll notify event to all listeners.
 func (n *Notifier) NotifyAll(event *Event) {
 	n.mu.Lock()
@@ -50,3 +75,33 @@ func AddEventListener(listener Listener) {
 	defer notifier.mu.Unlock()
 	notifier.listeners = append(notifier.listeners, listener)
 }
+
+// logListener is a listener which logs the events.
+type logListener struct {
+	logger *log.Logger
+	types  map[EventType]bool
+}
+
+// NewLogListener returns a new listener which logs the events of types by logger.
+// If types aren't given, it logs the all events.
+// e.g. logs warnings when the expired features are invoked:
+//
+//	AddEventListener(NewLogListener(logger, EventFeatureExpired))
+func NewLogListener(logger *log.Logger, types ...EventType) Listener {
+	listener := &logListener{
+		logger: logger,
+		types:  make(map[EventType]bool),
+	}
+	for _, typ := range types {
+		listener.types[typ] = true
+	}
+	return listener
+}
+
+// Listen logs event.
+func (l *logListener) Listen(event *Event) {
+	if len(l.types) > 0 && !l.types[event.Type] {
+		return
+	}
+	l.logger.Printf("gocchan: %v: feature `%s` method `%s`: %v", event.Type, event.FeatureName, event.FuncName, event.Err)
+}
@@ -1,7 +1,11 @@
 package gocchan
 
 import (
+	"bytes"
+	"fmt"
+	"log"
 	"reflect"
+	"sort"
 	"testing"
 )
 
@@ -65,3 +69,83 @@ func Test_AddEventListener(t *testing.T) {
 		t.Errorf("Expect %q, but %q", expected, actual)
 	}
 }
+
+func Test_NewLogListener(t *testing.T) {
+	var buf bytes.Buffer
+	listener := NewLogListener(log.New(&buf, "", 0), EventFeatureExpired)
+	listener.Listen(&Event{Type: EventFeatureInvoked, FeatureName: "test", FuncName: "Func1"})
+	listener.Listen(&Event{Type: EventFeatureExpired, Err: "expired", FeatureName: "test", FuncName: "Func1"})
+	actual := buf.String()
+	expected := "gocchan: EventFeatureExpired: feature `test` method `Func1`: expired\n"
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Expect %q, but %q", expected, actual)
+	}
+
+	buf.Reset()
+	listener = NewLogListener(log.New(&buf, "", 0))
+	listener.Listen(&Event{Type: EventFeatureInvoked, FeatureName: "test", FuncName: "Func1"})
+	actual = buf.String()
+	expected = "gocchan: EventFeatureInvoked: feature `test` method `Func1`: <nil>\n"
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Expect %q, but %q", expected, actual)
+	}
+}
+
+func Test_NotifyInvocations(t *testing.T) {
+	defer Isolate()()
+	events := make(chanListener, 10)
+	AddEventListener(events)
+	AddFeature("test", &TestFeature{t, "test", true, nil, nil})
+	Invoke("ctx", "test", "Func1", nil)
+	WaitNotify()
+	if len(events) != 0 {
+		t.Errorf("events of invocation have been notified by default: %v", <-events)
+	}
+
+	NotifyInvocations(true)
+	Invoke("ctx", "test", "Func1", nil)
+	Invoke("ctx", "test", "Missing", nil)
+	WaitNotify()
+	close(events)
+	var actual []EventType
+	for event := range events {
+		actual = append(actual, event.Type)
+	}
+	sort.Slice(actual, func(i, j int) bool {
+		return actual[i] < actual[j]
+	})
+	expected := []EventType{EventFeatureMethodMissing, EventFeatureInvoked, EventDefaultInvoked}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Expect %v, but %v", expected, actual)
+	}
+}
+
+type nopListener struct{}
+
+func (nopListener) Listen(event *Event) {}
+
+func BenchmarkNotifier_NotifyAll(b *testing.B) {
+	for _, n := range []int{1, 10, 100} {
+		notifier := &Notifier{}
+		for i := 0; i < n; i++ {
+			notifier.listeners = append(notifier.listeners, nopListener{})
+		}
+		event := NewEvent(EventFeatureInvoked, nil)
+		b.Run(fmt.Sprintf("%d listeners", n), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				notifier.NotifyAll(event)
+			}
+			notifier.Wait()
+		})
+		b.Run(fmt.Sprintf("%d listeners parallel", n), func(b *testing.B) {
+			b.ReportAllocs()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					notifier.NotifyAll(event)
+				}
+			})
+			notifier.Wait()
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package gocchan
+
+import "time"
+
+// Option represents an option of feature for AddFeature.
+type Option func(*status)
+
+// Metadata represents the metadata of feature.
+type Metadata struct {
+	// owner of feature.
+	Owner string
+
+	// description of feature.
+	Description string
+
+	// tags of feature.
+	Tags []string
+
+	// date when the feature was created.
+	Created time.Time
+
+	// date when the feature should be removed.
+	// The zero value means that the feature never expires.
+	Expires time.Time
+}
+
+// Owner returns an option that sets owner of feature.
+func Owner(owner string) Option {
+	return func(st *status) {
+		st.metadata.Owner = owner
+	}
+}
+
+// Description returns an option that sets description of feature.
+func Description(description string) Option {
+	return func(st *status) {
+		st.metadata.Description = description
+	}
+}
+
+// Tags returns an option that adds tags to feature.
+func Tags(tags ...string) Option {
+	return func(st *status) {
+		st.metadata.Tags = append(st.metadata.Tags, tags...)
+	}
+}
+
+// Created returns an option that sets the date when the feature was created.
+func Created(t time.Time) Option {
+	return func(st *status) {
+		st.metadata.Created = t
+	}
+}
+
+// Expires returns an option that sets the date when the feature should be removed.
+// Invoking the expired feature emits EventFeatureExpired.
+func Expires(t time.Time) Option {
+	return func(st *status) {
+		st.metadata.Expires = t
+	}
+}
@@ -0,0 +1,80 @@
+package gocchan
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+// chanListener is a listener which sends the events to channel.
+type chanListener chan *Event
+
+func (l chanListener) Listen(event *Event) {
+	l <- event
+}
+
+func Test_AddFeature_options(t *testing.T) {
+	defer Isolate()()
+	created := time.Date(2014, 1, 1, 0, 0, 0, 0, time.UTC)
+	expires := time.Date(2014, 12, 31, 0, 0, 0, 0, time.UTC)
+	feature := &TestFeature{t, "test", true, nil, nil}
+	AddFeature("test", feature, Owner("naoina"), Description("test feature"), Tags("a", "b"), Tags("c"), Created(created), Expires(expires))
+	actual := featureStatus["test"].metadata
+	expected := Metadata{
+		Owner:       "naoina",
+		Description: "test feature",
+		Tags:        []string{"a", "b", "c"},
+		Created:     created,
+		Expires:     expires,
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Expect %v, but %v", expected, actual)
+	}
+}
+
+func Test_Features(t *testing.T) {
+	defer Isolate()()
+	SetClock(ClockFunc(func() time.Time { return time.Date(2014, 6, 1, 0, 0, 0, 0, time.UTC) }))
+	expires := time.Date(2014, 3, 1, 0, 0, 0, 0, time.UTC)
+	AddFeature("b", &TestFeature{t, "b", true, nil, nil}, Owner("owner b"), Expires(expires))
+	AddFeature("a", &TestFeature{t, "a", true, nil, nil}, Owner("owner a"))
+	featureStatus["a"].fault = true
+	actual := Features()
+	expected := []*FeatureInfo{
+		{Metadata: Metadata{Owner: "owner a"}, Name: "a", Active: false, Expired: false},
+		{Metadata: Metadata{Owner: "owner b", Expires: expires}, Name: "b", Active: true, Expired: true},
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Expect %v, but %v", expected, actual)
+	}
+}
+
+func Test_Invoke_expired(t *testing.T) {
+	defer Isolate()()
+	SetClock(ClockFunc(func() time.Time { return time.Date(2014, 6, 1, 0, 0, 0, 0, time.UTC) }))
+	events := make(chanListener, 10)
+	AddEventListener(events)
+	feature := &TestFeature{t, "test", true, nil, nil}
+	AddFeature("test", feature, Expires(time.Date(2014, 6, 1, 0, 0, 0, 0, time.UTC)))
+	Invoke("ctx", "test", "Func1", nil)
+	WaitNotify()
+	close(events)
+	var actual []EventType
+	for event := range events {
+		if event.Type == EventFeatureExpired && (event.FeatureName != "test" || event.FuncName != "Func1") {
+			t.Errorf("Expect test and Func1, but %v and %v", event.FeatureName, event.FuncName)
+		}
+		actual = append(actual, event.Type)
+	}
+	sort.Slice(actual, func(i, j int) bool {
+		return actual[i] < actual[j]
+	})
+	expected := []EventType{EventFeatureExpired}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Expect %v, but %v", expected, actual)
+	}
+	if !reflect.DeepEqual(feature.calledBy, []string{"Func1:ctx"}) {
+		t.Errorf("expired feature hasn't been invoked")
+	}
+}
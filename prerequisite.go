@@ -0,0 +1,69 @@
+package gocchan
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Requires returns an option that declares the prerequisite features.
+// The feature is active only when the all prerequisite features have been added, aren't fault and are active
+// with the same context and options.
+// The prerequisite is regarded as fault if any of its methods is fault, until it is reset by ResetFault or recovered.
+// The prerequisite is checked without side effects, so neither its sticky assignment is stored nor its tokens of the rate limit are taken.
+func Requires(featureNames ...string) Option {
+	return func(st *status) {
+		st.requires = append(st.requires, featureNames...)
+	}
+}
+
+// Dependencies returns the dependency graph of features.
+// It maps the name of feature to the names of its prerequisite features.
+func Dependencies() map[string][]string {
+	graph := make(map[string][]string)
+	for name, st := range featureStatus {
+		if len(st.requires) > 0 {
+			graph[name] = append([]string(nil), st.requires...)
+		}
+	}
+	return graph
+}
+
+// checkCycle panics if the prerequisites of the feature named featureName make a cycle.
+func checkCycle(featureName string, requires []string) {
+	visiting := make(map[string]bool)
+	var visit func(name string, path []string)
+	visit = func(name string, path []string) {
+		path = append(path, name)
+		if name == featureName && len(path) > 1 {
+			panic(fmt.Sprintf("prerequisites of feature `%s` make a cycle: %s", featureName, strings.Join(path, " -> ")))
+		}
+		if visiting[name] {
+			return
+		}
+		visiting[name] = true
+		deps := requires
+		if name != featureName {
+			if st := featureStatus[name]; st != nil {
+				deps = st.requires
+			} else {
+				deps = nil
+			}
+		}
+		for _, dep := range deps {
+			visit(dep, path)
+		}
+	}
+	visit(featureName, nil)
+}
+
+// prerequisitesActive returns whether the all prerequisites of the feature are active.
+func prerequisitesActive(status *status, context interface{}, options ...interface{}) bool {
+	for _, name := range status.requires {
+		st := featureStatus[name]
+		// the prerequisite is checked without side effects, since it isn't used by the check.
+		if st == nil || st.faulted() || !peek(st, name, context, options...) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,99 @@
+package gocchan
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_Requires(t *testing.T) {
+	defer Isolate()()
+	parent := &TestFeature{t, "parent", true, nil, nil}
+	child := &TestFeature{t, "child", true, nil, nil}
+	AddFeature("child", child, Requires("parent"))
+	if ActiveIf("child", "ctx") {
+		t.Errorf("ActiveIf returns true without prerequisite")
+	}
+	AddFeature("parent", parent)
+	if !ActiveIf("child", "ctx", "opt1") {
+		t.Errorf("ActiveIf returns false with active prerequisite")
+	}
+	actual := parent.activeIfCalledBy
+	expected := []string{"ctx:[opt1]"}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Expect %q, but %q", expected, actual)
+	}
+
+	parent.active = false
+	if ActiveIf("child", "ctx") {
+		t.Errorf("ActiveIf returns true with inactive prerequisite")
+	}
+	called := false
+	Invoke("ctx", "child", "Func1", func() {
+		called = true
+	})
+	if !called {
+		t.Errorf("defaultFunc hasn't been called with inactive prerequisite")
+	}
+
+	parent.active = true
+	Invoke("ctx", "parent", "FuncPanic", nil)
+	if ActiveIf("child", "ctx") {
+		t.Errorf("ActiveIf returns true with fault prerequisite")
+	}
+	ResetFault("parent", "FuncPanic")
+	if !ActiveIf("child", "ctx") {
+		t.Errorf("ActiveIf returns false after fault of prerequisite is reset")
+	}
+}
+
+func Test_Requires_cycle(t *testing.T) {
+	defer Isolate()()
+	AddFeature("a", &TestFeature{t, "a", true, nil, nil}, Requires("b"))
+	AddFeature("b", &TestFeature{t, "b", true, nil, nil}, Requires("c"))
+	for _, v := range []struct {
+		name     string
+		requires []string
+	}{
+		{"c", []string{"a"}},
+		{"d", []string{"d"}},
+		{"a", []string{"a"}},
+	} {
+		func() {
+			defer func() {
+				if err := recover(); err == nil {
+					t.Errorf("panic doesn't occurred by cycle of %v", v.name)
+				}
+			}()
+			AddFeature(v.name, &TestFeature{t, v.name, true, nil, nil}, Requires(v.requires...))
+		}()
+	}
+	AddFeature("c", &TestFeature{t, "c", true, nil, nil})
+	AddFeature("d", &TestFeature{t, "d", true, nil, nil}, Requires("a", "c"))
+	actual := Dependencies()
+	expected := map[string][]string{
+		"a": {"b"},
+		"b": {"c"},
+		"d": {"a", "c"},
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Expect %v, but %v", expected, actual)
+	}
+}
+
+func Test_Requires_sticky(t *testing.T) {
+	defer Isolate()()
+	SetAssignmentStore(NewMemoryAssignmentStore(10))
+	parent := &TestFeature{t, "parent", false, nil, nil}
+	AddFeature("parent", parent)
+	AddFeature("child", &TestFeature{t, "child", true, nil, nil}, Requires("parent"))
+	if ActiveIf("child", "subject") {
+		t.Errorf("ActiveIf returns true with inactive prerequisite")
+	}
+	if a, ok := loadAssignment("parent", "subject"); ok {
+		t.Errorf("assignment of prerequisite has been stored by child: %v", a)
+	}
+	parent.active = true
+	if !ActiveIf("parent", "subject") {
+		t.Errorf("ActiveIf returns false after prerequisite is activated")
+	}
+}
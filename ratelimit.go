@@ -0,0 +1,137 @@
+package gocchan
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// rateLimiter is a token bucket limiter of the activations of feature.
+type rateLimiter struct {
+	// number of tokens which are added per second.
+	rate float64
+
+	// maximum number of tokens.
+	burst float64
+
+	// Whether the bucket is provided per subject.
+	perSubject bool
+
+	// buckets by subject key. The shared bucket has an empty key.
+	buckets map[string]*bucket
+
+	// number of buckets to sweep the full buckets.
+	sweepAt int
+
+	mu sync.Mutex
+}
+
+// bucket represents a token bucket.
+type bucket struct {
+	tokens float64
+
+	// time when the tokens were updated.
+	last time.Time
+}
+
+// RateLimit returns an option that limits the activations of the feature to n per second with a burst of burst.
+// The feature is inactive while the tokens are exhausted, so the defaultFunc is invoked instead of the method.
+// The forced activation by Override isn't limited.
+// The token is taken only when the feature itself is checked, and after the slot of MaxConcurrency is acquired,
+// so the checks of the feature as a prerequisite and the fallbacks by the concurrency limit don't consume it.
+// The number of throttled activations can be retrieved by Throttled.
+// If n or burst isn't positive, it panic.
+func RateLimit(n float64, burst int) Option {
+	return rateLimit(n, burst, false)
+}
+
+// RateLimitPerSubject is like RateLimit but limits the activations per subject.
+// The subjects which can't be identified by context share a bucket. See Subject.
+func RateLimitPerSubject(n float64, burst int) Option {
+	return rateLimit(n, burst, true)
+}
+
+func rateLimit(n float64, burst int, perSubject bool) Option {
+	if n <= 0 || burst <= 0 {
+		panic(fmt.Sprintf("rate and burst must be positive: %v, %d", n, burst))
+	}
+	return func(st *status) {
+		st.limiter = &rateLimiter{
+			rate:       n,
+			burst:      float64(burst),
+			perSubject: perSubject,
+			buckets:    make(map[string]*bucket),
+			sweepAt:    minSweepAt,
+		}
+	}
+}
+
+// throttle returns true if the activation of the feature is allowed by the rate limit, otherwise returns false.
+// The throttled activation is counted.
+func throttle(status *status, context interface{}) bool {
+	l := status.limiter
+	if l == nil {
+		return true
+	}
+	var key string
+	if l.perSubject {
+		key, _ = subjectKey(context)
+	}
+	if l.take(key, now()) {
+		return true
+	}
+	atomic.AddUint64(&status.throttled, 1)
+	return false
+}
+
+// take takes a token from the bucket of key at t and returns true if it is taken, otherwise returns false.
+func (l *rateLimiter) take(key string, t time.Time) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	b := l.buckets[key]
+	if b == nil {
+		l.sweep(t)
+		b = &bucket{tokens: l.burst, last: t}
+		l.buckets[key] = b
+	}
+	b.refill(t, l.rate, l.burst)
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// refill adds the tokens which are accumulated until t.
+func (b *bucket) refill(t time.Time, rate, burst float64) {
+	if elapsed := t.Sub(b.last); elapsed > 0 {
+		b.tokens = math.Min(burst, b.tokens+elapsed.Seconds()*rate)
+		b.last = t
+	}
+}
+
+// sweep removes the buckets which are full at t.
+func (l *rateLimiter) sweep(t time.Time) {
+	if len(l.buckets) < l.sweepAt {
+		return
+	}
+	for key, b := range l.buckets {
+		if b.refill(t, l.rate, l.burst); b.tokens >= l.burst {
+			delete(l.buckets, key)
+		}
+	}
+	if l.sweepAt = len(l.buckets) * 2; l.sweepAt < minSweepAt {
+		l.sweepAt = minSweepAt
+	}
+}
+
+// Throttled returns the number of activations of the feature which have been throttled by the rate limit.
+func Throttled(featureName string) uint64 {
+	status := featureStatus[featureName]
+	if status == nil {
+		return 0
+	}
+	return atomic.LoadUint64(&status.throttled)
+}
@@ -0,0 +1,138 @@
+package gocchan
+
+import (
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func Test_RateLimit_invalid(t *testing.T) {
+	for _, v := range []struct {
+		n     float64
+		burst int
+	}{
+		{0, 1},
+		{1, 0},
+	} {
+		func() {
+			defer func() {
+				if err := recover(); err == nil {
+					t.Errorf("panic doesn't occurred by %v", v)
+				}
+			}()
+			RateLimit(v.n, v.burst)
+		}()
+	}
+}
+
+func Test_RateLimit(t *testing.T) {
+	defer Isolate()()
+	current := time.Date(2014, 6, 1, 0, 0, 0, 0, time.UTC)
+	SetClock(ClockFunc(func() time.Time { return current }))
+	AddFeature("test", &TestFeature{t, "test", true, nil, nil}, RateLimit(2, 2))
+	var actual []bool
+	for i := 0; i < 3; i++ {
+		actual = append(actual, ActiveIf("test", "ctx"))
+	}
+	current = current.Add(500 * time.Millisecond)
+	for i := 0; i < 2; i++ {
+		actual = append(actual, ActiveIf("test", "ctx"))
+	}
+	current = current.Add(time.Hour)
+	for i := 0; i < 3; i++ {
+		actual = append(actual, ActiveIf("test", "other"))
+	}
+	expected := []bool{true, true, false, true, false, true, true, false}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Expect %v, but %v", expected, actual)
+	}
+	var throttled interface{} = Throttled("test")
+	var expectedThrottled interface{} = uint64(3)
+	if !reflect.DeepEqual(throttled, expectedThrottled) {
+		t.Errorf("Expect %v, but %v", expectedThrottled, throttled)
+	}
+	if info := Features()[0]; info.Throttled != 3 {
+		t.Errorf("Expect 3, but %v", info.Throttled)
+	}
+
+	called := false
+	Invoke("ctx", "test", "Func1", func() {
+		called = true
+	})
+	if !called {
+		t.Errorf("defaultFunc hasn't been called by throttled feature")
+	}
+	Override("test", true)
+	if !ActiveIf("test", "ctx") {
+		t.Errorf("forced activation has been throttled")
+	}
+	if n := Throttled("unknown"); n != 0 {
+		t.Errorf("Expect 0, but %v", n)
+	}
+}
+
+func Test_RateLimit_tokens(t *testing.T) {
+	defer Isolate()()
+	current := time.Date(2014, 6, 1, 0, 0, 0, 0, time.UTC)
+	SetClock(ClockFunc(func() time.Time { return current }))
+	AddFeature("parent", &TestFeature{t, "parent", true, nil, nil}, RateLimit(1, 1))
+	AddFeature("child", &TestFeature{t, "child", true, nil, nil}, Requires("parent"))
+	for i := 0; i < 2; i++ {
+		if !ActiveIf("child", "ctx") {
+			t.Errorf("child is inactive by the rate limit of prerequisite")
+		}
+	}
+	if n := Throttled("parent"); n != 0 {
+		t.Errorf("Expect 0, but %v", n)
+	}
+	if !ActiveIf("parent", "ctx") {
+		t.Errorf("token of prerequisite has been taken by child")
+	}
+
+	feature := &blockingFeature{started: make(chan struct{}, 1), release: make(chan struct{})}
+	AddFeature("limited", feature, MaxConcurrency(1), RateLimit(1, 2))
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		Invoke("ctx", "limited", "Func", nil)
+	}()
+	<-feature.started
+	Invoke("ctx", "limited", "Func", nil)
+	close(feature.release)
+	<-done
+	if !ActiveIf("limited", "ctx") {
+		t.Errorf("token has been taken by the fallback of concurrency limit")
+	}
+}
+
+func Test_RateLimitPerSubject(t *testing.T) {
+	defer Isolate()()
+	current := time.Date(2014, 6, 1, 0, 0, 0, 0, time.UTC)
+	SetClock(ClockFunc(func() time.Time { return current }))
+	AddFeature("test", &TestFeature{t, "test", true, nil, nil}, RateLimitPerSubject(1, 1))
+	var actual []bool
+	for _, subject := range []interface{}{"a", "a", "b", nil, 1} {
+		actual = append(actual, ActiveIf("test", subject))
+	}
+	expected := []bool{true, false, true, true, false}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Expect %v, but %v", expected, actual)
+	}
+}
+
+func Test_rateLimiter_sweep(t *testing.T) {
+	current := time.Date(2014, 6, 1, 0, 0, 0, 0, time.UTC)
+	st := &status{}
+	RateLimitPerSubject(1, 1)(st)
+	l := st.limiter
+	for i := 0; i < minSweepAt; i++ {
+		l.take(strconv.Itoa(i), current)
+	}
+	l.take("last", current.Add(time.Second))
+	actual := len(l.buckets)
+	expected := 1
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Expect %v, but %v", expected, actual)
+	}
+}
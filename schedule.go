@@ -0,0 +1,187 @@
+package gocchan
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule is an interface of schedule of feature activation.
+type Schedule interface {
+	// Active returns whether the feature is active at t.
+	Active(t time.Time) bool
+}
+
+// Scheduled returns an option that activates the feature only while any of schedules is active.
+// The schedules are evaluated with the global clock alongside ActiveIf of Feature. See SetClock.
+func Scheduled(schedules ...Schedule) Option {
+	return func(st *status) {
+		st.schedules = append(st.schedules, schedules...)
+	}
+}
+
+// scheduled returns whether any of schedules of the feature is active at now.
+// If the feature has no schedules, it returns true.
+func scheduled(status *status) bool {
+	if len(status.schedules) == 0 {
+		return true
+	}
+	t := now()
+	for _, s := range status.schedules {
+		if s.Active(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// window is a Schedule which is active in an absolute time window.
+type window struct {
+	start, end time.Time
+}
+
+// Between returns a new Schedule which is active from start until end.
+// If start is zero, it is active until end. If end is zero, it is active after start.
+func Between(start, end time.Time) Schedule {
+	return &window{start: start, end: end}
+}
+
+func (w *window) Active(t time.Time) bool {
+	return (w.start.IsZero() || !t.Before(w.start)) && (w.end.IsZero() || t.Before(w.end))
+}
+
+// cron is a Schedule which is active for a duration after each time matched the cron spec.
+type cron struct {
+	minute, hour, dom, month, dow []bool
+
+	// Whether day of month and day of week are restricted.
+	domRestricted, dowRestricted bool
+
+	duration time.Duration
+}
+
+// Cron returns a new Schedule which is active for d after each time matched spec.
+// spec is the standard cron format that consists of five fields: minute, hour, day of month, month and day of week.
+// Each field accepts "*", numbers, ranges ("1-5"), steps ("*/15", "0-30/10") and lists of them ("1,15").
+// The step from a number ("5/15") is from the number to the maximum, as well as "5-59/15".
+// Both 0 and 7 of the day of week are Sunday.
+// e.g. active for 2 hours from 12:00 on every weekday:
+//
+//	Cron("0 12 * * 1-5", 2*time.Hour)
+func Cron(spec string, d time.Duration) (Schedule, error) {
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("cron spec must have five fields: `%s`", spec)
+	}
+	if d <= 0 {
+		return nil, fmt.Errorf("duration of cron schedule must be positive: %v", d)
+	}
+	c := &cron{
+		duration:      d,
+		domRestricted: fields[2] != "*",
+		dowRestricted: fields[4] != "*",
+	}
+	for i, f := range []struct {
+		field    *[]bool
+		min, max int
+	}{
+		{&c.minute, 0, 59},
+		{&c.hour, 0, 23},
+		{&c.dom, 1, 31},
+		{&c.month, 1, 12},
+		{&c.dow, 0, 7},
+	} {
+		field, err := parseCronField(fields[i], f.min, f.max)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cron spec `%s`: %v", spec, err)
+		}
+		*f.field = field
+	}
+	c.dow[0] = c.dow[0] || c.dow[7]
+	c.dow = c.dow[:7]
+	return c, nil
+}
+
+// MustCron is like Cron but panics if spec can't be parsed.
+func MustCron(spec string, d time.Duration) Schedule {
+	s, err := Cron(spec, d)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+// parseCronField parses a field of cron spec and returns the matched values.
+func parseCronField(field string, min, max int) ([]bool, error) {
+	values := make([]bool, max+1)
+	for _, part := range strings.Split(field, ",") {
+		rng, step := part, 1
+		i := strings.IndexByte(part, '/')
+		if i >= 0 {
+			n, err := strconv.Atoi(part[i+1:])
+			if err != nil || n <= 0 {
+				return nil, fmt.Errorf("invalid step: `%s`", part)
+			}
+			rng, step = part[:i], n
+		}
+		start, end := min, max
+		if rng != "*" {
+			bounds := strings.SplitN(rng, "-", 2)
+			var err error
+			if start, err = strconv.Atoi(bounds[0]); err != nil {
+				return nil, fmt.Errorf("invalid value: `%s`", part)
+			}
+			switch {
+			case len(bounds) == 2:
+				if end, err = strconv.Atoi(bounds[1]); err != nil {
+					return nil, fmt.Errorf("invalid value: `%s`", part)
+				}
+			case i >= 0:
+				// the step from a number is up to the maximum.
+				end = max
+			default:
+				end = start
+			}
+		}
+		if start < min || end > max || start > end {
+			return nil, fmt.Errorf("out of range [%d-%d]: `%s`", min, max, part)
+		}
+		for v := start; v <= end; v += step {
+			values[v] = true
+		}
+	}
+	return values, nil
+}
+
+// Active returns whether t is within the duration after the latest time matched the spec.
+func (c *cron) Active(t time.Time) bool {
+	limit := t.Add(-c.duration)
+	s := time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), 0, 0, t.Location())
+	for s.After(limit) {
+		switch {
+		case !c.matchDay(s):
+			s = time.Date(s.Year(), s.Month(), s.Day(), 0, 0, 0, 0, s.Location()).Add(-time.Minute)
+		case !c.hour[s.Hour()]:
+			s = time.Date(s.Year(), s.Month(), s.Day(), s.Hour(), 0, 0, 0, s.Location()).Add(-time.Minute)
+		case !c.minute[s.Minute()]:
+			s = s.Add(-time.Minute)
+		default:
+			return true
+		}
+	}
+	return false
+}
+
+// matchDay returns whether the date of t matches the spec.
+// As with the standard cron, if both day of month and day of week are restricted, either of them must match.
+func (c *cron) matchDay(t time.Time) bool {
+	if !c.month[t.Month()] {
+		return false
+	}
+	dom, dow := c.dom[t.Day()], c.dow[t.Weekday()]
+	if c.domRestricted && c.dowRestricted {
+		return dom || dow
+	}
+	return dom && dow
+}
@@ -0,0 +1,122 @@
+package gocchan
+
+import (
+	"testing"
+	"time"
+)
+
+func Test_Between(t *testing.T) {
+	start := time.Date(2014, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2014, 2, 1, 0, 0, 0, 0, time.UTC)
+	for _, v := range []struct {
+		start, end time.Time
+		t          time.Time
+		expected   bool
+	}{
+		{start, end, start.Add(-time.Second), false},
+		{start, end, start, true},
+		{start, end, end.Add(-time.Second), true},
+		{start, end, end, false},
+		{time.Time{}, end, start.Add(-time.Hour), true},
+		{start, time.Time{}, end.Add(time.Hour), true},
+	} {
+		actual := Between(v.start, v.end).Active(v.t)
+		if actual != v.expected {
+			t.Errorf("Between(%v, %v).Active(%v) expect %v, but %v", v.start, v.end, v.t, v.expected, actual)
+		}
+	}
+}
+
+func Test_Cron(t *testing.T) {
+	for _, spec := range []string{
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"a * * * *",
+		"5-1 * * * *",
+	} {
+		if _, err := Cron(spec, time.Hour); err == nil {
+			t.Errorf("Cron(%q) doesn't return error", spec)
+		}
+	}
+	if _, err := Cron("* * * * *", 0); err == nil {
+		t.Errorf("Cron doesn't return error by duration of zero")
+	}
+
+	// 2014-01-06 is Monday.
+	date := func(day, hour, min int) time.Time {
+		return time.Date(2014, 1, day, hour, min, 30, 0, time.UTC)
+	}
+	for _, v := range []struct {
+		spec     string
+		d        time.Duration
+		t        time.Time
+		expected bool
+	}{
+		{"0 12 * * 1-5", 2 * time.Hour, date(6, 11, 59), false},
+		{"0 12 * * 1-5", 2 * time.Hour, date(6, 12, 0), true},
+		{"0 12 * * 1-5", 2 * time.Hour, date(6, 13, 59), true},
+		{"0 12 * * 1-5", 2 * time.Hour, date(6, 14, 0), false},
+		{"0 12 * * 1-5", 2 * time.Hour, date(5, 13, 0), false},
+		{"0 23 * * 1-5", 2 * time.Hour, date(7, 0, 30), true},
+		{"*/15 * * * *", time.Minute, date(6, 10, 30), true},
+		{"*/15 * * * *", time.Minute, date(6, 10, 31), false},
+		{"0,30 9-10 * * *", 10 * time.Minute, date(6, 10, 35), true},
+		{"0,30 9-10 * * *", 10 * time.Minute, date(6, 11, 5), false},
+		{"0 0 1 * *", 24 * time.Hour, date(1, 23, 59), true},
+		{"0 0 1 * *", 24 * time.Hour, date(2, 0, 0), false},
+		{"0 0 15 * 1", time.Hour, date(6, 0, 30), true},
+		{"0 0 15 * 1", time.Hour, date(15, 0, 30), true},
+		{"0 0 15 * 1", time.Hour, date(14, 0, 30), false},
+		{"0 0 1 1 *", 24 * 40 * time.Hour, date(31, 12, 0), true},
+		{"5/15 * * * *", time.Minute, date(6, 10, 5), true},
+		{"5/15 * * * *", time.Minute, date(6, 10, 50), true},
+		{"5/15 * * * *", time.Minute, date(6, 10, 0), false},
+		{"0 0 * * 7", time.Hour, date(5, 0, 30), true},
+		{"0 0 * * 7", time.Hour, date(6, 0, 30), false},
+		{"0 0 * * 0", time.Hour, date(5, 0, 30), true},
+		{"0 0 * * 5-7", time.Hour, date(4, 0, 30), true},
+	} {
+		actual := MustCron(v.spec, v.d).Active(v.t)
+		if actual != v.expected {
+			t.Errorf("Cron(%q, %v).Active(%v) expect %v, but %v", v.spec, v.d, v.t, v.expected, actual)
+		}
+	}
+}
+
+func Test_Scheduled(t *testing.T) {
+	defer Isolate()()
+	current := time.Date(2014, 1, 1, 0, 0, 0, 0, time.UTC)
+	SetClock(ClockFunc(func() time.Time { return current }))
+	feature := &TestFeature{t, "test", true, nil, nil}
+	AddFeature("test", feature, Scheduled(
+		Between(time.Date(2014, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2014, 1, 2, 0, 0, 0, 0, time.UTC)),
+		MustCron("0 12 * * *", time.Hour),
+	))
+	for _, v := range []struct {
+		t        time.Time
+		expected bool
+	}{
+		{time.Date(2013, 12, 31, 23, 0, 0, 0, time.UTC), false},
+		{time.Date(2013, 12, 31, 12, 0, 0, 0, time.UTC), true},
+		{time.Date(2014, 1, 1, 0, 0, 0, 0, time.UTC), true},
+		{time.Date(2014, 1, 2, 0, 0, 0, 0, time.UTC), false},
+		{time.Date(2014, 1, 2, 12, 59, 0, 0, time.UTC), true},
+	} {
+		current = v.t
+		actual := ActiveIf("test", "ctx")
+		if actual != v.expected {
+			t.Errorf("ActiveIf at %v expect %v, but %v", v.t, v.expected, actual)
+		}
+	}
+
+	feature.active = false
+	current = time.Date(2014, 1, 1, 0, 0, 0, 0, time.UTC)
+	if ActiveIf("test", "ctx") {
+		t.Errorf("ActiveIf returns true by schedule even if ActiveIf of Feature returns false")
+	}
+}
@@ -0,0 +1,117 @@
+package gocchan
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// ShadowResult represents the results of shadow execution.
+// It is passed as Err of EventFeatureShadowMismatch.
+type ShadowResult struct {
+	// result of the defaultFunc.
+	Default interface{}
+
+	// result of the method of feature.
+	Feature interface{}
+
+	// elapsed time of the defaultFunc.
+	DefaultElapsed time.Duration
+
+	// elapsed time of the method of feature.
+	FeatureElapsed time.Duration
+}
+
+func (r *ShadowResult) String() string {
+	return fmt.Sprintf("default %#v (%v), but feature %#v (%v)", r.Default, r.DefaultElapsed, r.Feature, r.FeatureElapsed)
+}
+
+// shadows is a group of the running shadow executions.
+var shadows sync.WaitGroup
+
+// WaitShadow blocks until the all shadow executions are finished.
+func WaitShadow() {
+	shadows.Wait()
+}
+
+// Shadow invokes defaultFunc and returns its result as the authoritative result.
+// In addition, will invoke the method named funcName in the background (shadow execution)
+// if the feature is active, as well as Invoke.
+// The shadow execution is subject to the variants, the timeout, the concurrency limit, the rate limit and the trials
+// of the feature as well as Invoke.
+// The method must return exactly one value, and it is compared with the result of defaultFunc by compare.
+// If compare is nil, reflect.DeepEqual is used.
+// When the results mismatched, EventFeatureShadowMismatch is notified with ShadowResult.
+// The panic in the method doesn't affect the caller, but the method will be fault as well as Invoke.
+func Shadow(context interface{}, featureName, funcName string, defaultFunc func() interface{}, compare func(defaultResult, featureResult interface{}) bool, options ...interface{}) interface{} {
+	if compare == nil {
+		compare = reflect.DeepEqual
+	}
+	status := featureStatus[featureName]
+	defaults := make(chan *ShadowResult, 1)
+	shadows.Add(1)
+	go func() {
+		defer shadows.Done()
+		shadow(status, context, featureName, funcName, defaults, compare, options...)
+	}()
+	// closes defaults without a result if defaultFunc panics, not to block the shadow execution.
+	defer close(defaults)
+	start := now()
+	result := &ShadowResult{Default: defaultFunc()}
+	result.DefaultElapsed = now().Sub(start)
+	defaults <- result
+	return result.Default
+}
+
+// shadow invokes the method named funcName, and compares its result with the result of defaultFunc which is received from defaults.
+// The comparison is skipped if defaults is closed without a result.
+func shadow(status *status, context interface{}, featureName, funcName string, defaults <-chan *ShadowResult, compare func(defaultResult, featureResult interface{}) bool, options ...interface{}) {
+	defer func() {
+		if err := recover(); err != nil && err != ErrInvokeDefault {
+			status.recordFault(featureName, funcName, err)
+		}
+	}()
+	if status == nil {
+		err := fmt.Errorf("feature has not been added: `%s`", featureName)
+		notify(EventFeatureHasNotBeenAdded, featureName, funcName, err)
+		panic(ErrInvokeDefault)
+	}
+	if len(status.variants) > 0 {
+		variant, ok := assignVariant(status, featureName, context)
+		if !ok {
+			panic(ErrInvokeDefault)
+		}
+		funcName += variant
+	}
+	faulted := status.methodFault(funcName)
+	if faulted && !status.trialDue(funcName) {
+		panic(ErrInvokeDefault)
+	}
+	f, cvalue, _ := method(status, featureName, funcName, context, nil)
+	if f.Type().NumOut() != 1 {
+		err := fmt.Errorf("method signature mismatch: method `%s` in feature `%s` must return exactly one value for shadow execution", funcName, featureName)
+		notify(EventFeatureMethodSignatureMismatch, featureName, funcName, err)
+		panic(ErrInvokeDefault)
+	}
+	var recovered bool
+	if admit(status, featureName, funcName, faulted, context, options...) {
+		defer func() {
+			status.endTrial(featureName, funcName, recovered)
+		}()
+	}
+	start := now()
+	out := call(status, featureName, funcName, status.timeout, f, cvalue, nil)
+	elapsed := now().Sub(start)
+	status.recordSuccess(funcName)
+	recovered = status.recordLatency(featureName, funcName, elapsed)
+	result, ok := <-defaults
+	if !ok {
+		return
+	}
+	result.Feature = out[0].Interface()
+	result.FeatureElapsed = elapsed
+	if !compare(result.Default, result.Feature) {
+		notify(EventFeatureShadowMismatch, featureName, funcName, result)
+	}
+}
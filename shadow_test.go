@@ -0,0 +1,158 @@
+package gocchan
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type TestShadowFeature struct {
+	TestFeature
+}
+
+func (f *TestShadowFeature) Upper(context string) string {
+	return strings.ToUpper(context)
+}
+
+func (f *TestShadowFeature) Lower(context string) string {
+	return strings.ToLower(context)
+}
+
+func (f *TestShadowFeature) NoResult(context string) {
+}
+
+func (f *TestShadowFeature) Panic(context string) string {
+	panic("expected panic")
+}
+
+type TestShadowVariantFeature struct {
+	TestFeature
+}
+
+func (f *TestShadowVariantFeature) UpperA(context string) string {
+	return strings.ToUpper(context)
+}
+
+func (f *TestShadowVariantFeature) SlowA(context string) string {
+	time.Sleep(100 * time.Millisecond)
+	return context
+}
+
+func Test_Shadow_invoke(t *testing.T) {
+	defer Isolate()()
+	events := make(chanListener, 100)
+	AddEventListener(events)
+	feature := &TestShadowVariantFeature{TestFeature{t, "test", true, nil, nil}}
+	AddFeature("shadow", feature, Variants(Variant{"A", 1}), Timeout(10*time.Millisecond))
+	upper := func() interface{} {
+		return "ABC"
+	}
+	Shadow("abc", "shadow", "Upper", upper, nil)
+	WaitShadow()
+	Shadow("abc", "shadow", "Slow", upper, nil)
+	WaitShadow()
+	WaitNotify()
+	close(events)
+	var actual []EventType
+	for event := range events {
+		actual = append(actual, event.Type)
+	}
+	expected := []EventType{EventFeatureTimeout}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Expect %v, but %v", expected, actual)
+	}
+	if n := InFlight("shadow"); n != 1 {
+		t.Errorf("Expect timed out method to be in flight, but %v", n)
+	}
+}
+
+func Test_Shadow(t *testing.T) {
+	defer Isolate()()
+	events := make(chanListener, 100)
+	AddEventListener(events)
+	feature := &TestShadowFeature{TestFeature{t, "test", true, nil, nil}}
+	AddFeature("shadow", feature)
+	shadowEvents := func() []*Event {
+		WaitShadow()
+		WaitNotify()
+		var result []*Event
+		for len(events) > 0 {
+			result = append(result, <-events)
+		}
+		return result
+	}
+	upper := func() interface{} {
+		return "ABC"
+	}
+
+	var actual interface{} = Shadow("abc", "shadow", "Upper", upper, nil)
+	var expected interface{} = "ABC"
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Expect %q, but %q", expected, actual)
+	}
+	if events := shadowEvents(); len(events) != 0 {
+		t.Errorf("events have been notified by matched results: %v", events)
+	}
+
+	actual = Shadow("abc", "shadow", "Lower", upper, nil)
+	expected = "ABC"
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Expect %q, but %q", expected, actual)
+	}
+	mismatches := shadowEvents()
+	if len(mismatches) != 1 || mismatches[0].Type != EventFeatureShadowMismatch {
+		t.Fatalf("EventFeatureShadowMismatch hasn't been notified: %v", mismatches)
+	}
+	result := mismatches[0].Err.(*ShadowResult)
+	actual = []interface{}{result.Default, result.Feature}
+	expected = []interface{}{"ABC", "abc"}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Expect %q, but %q", expected, actual)
+	}
+
+	Shadow("abc", "shadow", "Lower", upper, func(defaultResult, featureResult interface{}) bool {
+		return strings.EqualFold(defaultResult.(string), featureResult.(string))
+	})
+	if events := shadowEvents(); len(events) != 0 {
+		t.Errorf("events have been notified by matched results with comparator: %v", events)
+	}
+
+	Shadow("abc", "shadow", "NoResult", upper, nil)
+	if events := shadowEvents(); len(events) != 1 || events[0].Type != EventFeatureMethodSignatureMismatch {
+		t.Errorf("EventFeatureMethodSignatureMismatch hasn't been notified: %v", events)
+	}
+
+	feature.active = false
+	Shadow("abc", "shadow", "Lower", upper, nil)
+	if events := shadowEvents(); len(events) != 0 {
+		t.Errorf("events have been notified by inactive feature: %v", events)
+	}
+
+	feature.active = true
+	actual = Shadow("abc", "shadow", "Panic", upper, nil)
+	expected = "ABC"
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Expect %q, but %q", expected, actual)
+	}
+	if events := shadowEvents(); len(events) != 1 || events[0].Type != EventFeatureWasFault {
+		t.Errorf("EventFeatureWasFault hasn't been notified: %v", events)
+	}
+	if IsMethodActive("shadow", "Panic") {
+		t.Errorf("method hasn't been fault by panic in shadow execution")
+	}
+
+	func() {
+		defer func() {
+			if err := recover(); err == nil {
+				t.Errorf("panic in defaultFunc hasn't been propagated")
+			}
+		}()
+		Shadow("abc", "shadow", "Lower", func() interface{} {
+			panic("expected panic")
+		}, nil)
+	}()
+	if events := shadowEvents(); len(events) != 0 {
+		t.Errorf("events have been notified by panic in defaultFunc: %v", events)
+	}
+}
@@ -0,0 +1,227 @@
+package gocchan
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Change represents a difference of state of a feature or a group between two snapshots.
+type Change struct {
+	FeatureName string
+
+	// name of method if the change is of a fault method.
+	FuncName string
+
+	// name of group if the change is of a group instead of a feature.
+	GroupName string
+
+	// "override", "fault" or "group".
+	Kind string
+
+	// state before and after the change. Empty means that the feature isn't overridden or the group doesn't exist.
+	// The state of group is the comma-separated member feature names.
+	Old string
+	New string
+}
+
+func (c Change) String() string {
+	if c.GroupName != "" {
+		return fmt.Sprintf("group `%s`: %s %q -> %q", c.GroupName, c.Kind, c.Old, c.New)
+	}
+	name := fmt.Sprintf("feature `%s`", c.FeatureName)
+	if c.FuncName != "" {
+		name += fmt.Sprintf(" method `%s`", c.FuncName)
+	}
+	return fmt.Sprintf("%s: %s %q -> %q", name, c.Kind, c.Old, c.New)
+}
+
+// Snapshot returns the current forced activations and fault state of features, and the groups of features.
+// It includes the state of the features which have been restored by the state store but haven't been added yet.
+// The other activation configs, such as the options of AddFeature, aren't included since they're defined by code.
+// The snapshot can be serialized as JSON, and be restored by Restore.
+func Snapshot() *State {
+	state := currentState()
+	groupsMu.RLock()
+	defer groupsMu.RUnlock()
+	for name, members := range groups {
+		if state.Groups == nil {
+			state.Groups = make(map[string][]string)
+		}
+		state.Groups[name] = append([]string(nil), members...)
+	}
+	return state
+}
+
+// Diff returns the changes from the snapshot from to the snapshot to, sorted by feature name, group name, method name and kind.
+func Diff(from, to *State) []Change {
+	var changes []Change
+	for name := range groupNames(from, to) {
+		if o, n := groupString(from, name), groupString(to, name); o != n {
+			changes = append(changes, Change{GroupName: name, Kind: "group", Old: o, New: n})
+		}
+	}
+	for name := range featureNames(from, to) {
+		f, t := featureState(from, name), featureState(to, name)
+		if o, n := overrideString(f.Override), overrideString(t.Override); o != n {
+			changes = append(changes, Change{FeatureName: name, Kind: "override", Old: o, New: n})
+		}
+		if f.Fault != t.Fault {
+			changes = append(changes, Change{FeatureName: name, Kind: "fault", Old: faultString(f.Fault), New: faultString(t.Fault)})
+		}
+		methods := make(map[string]int)
+		for _, m := range f.FaultedMethods {
+			methods[m]--
+		}
+		for _, m := range t.FaultedMethods {
+			methods[m]++
+		}
+		for m, d := range methods {
+			if d != 0 {
+				changes = append(changes, Change{FeatureName: name, FuncName: m, Kind: "fault", Old: faultString(d < 0), New: faultString(d > 0)})
+			}
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		a, b := changes[i], changes[j]
+		if a.FeatureName != b.FeatureName {
+			return a.FeatureName < b.FeatureName
+		}
+		if a.GroupName != b.GroupName {
+			return a.GroupName < b.GroupName
+		}
+		if a.FuncName != b.FuncName {
+			return a.FuncName < b.FuncName
+		}
+		return a.Kind > b.Kind
+	})
+	return changes
+}
+
+// Restore restores the forced activations and fault state of features, and the groups of features from snapshot.
+// The state and groups which aren't in snapshot are cleared. The features themselves aren't added or removed.
+// It returns the changes which have been made. See Diff.
+func Restore(snapshot *State) []Change {
+	return restoreSnapshot(Actor{}, snapshot)
+}
+
+// Restore is like Restore of the package but records the actor.
+func (a Actor) Restore(snapshot *State) []Change {
+	return restoreSnapshot(a, snapshot)
+}
+
+func restoreSnapshot(a Actor, snapshot *State) []Change {
+	changes := Diff(Snapshot(), snapshot)
+	for _, c := range changes {
+		if c.Kind == "group" {
+			storeGroup(c.GroupName, snapshot.Groups[c.GroupName])
+			continue
+		}
+		fs := featureState(snapshot, c.FeatureName)
+		if c.Kind == "override" {
+			if fs.Override == nil {
+				deleteOverride(c.FeatureName)
+			} else {
+				storeOverride(c.FeatureName, *fs.Override)
+			}
+			continue
+		}
+		st := featureStatus[c.FeatureName]
+		if st == nil {
+			if fs.Fault || len(fs.FaultedMethods) > 0 {
+				pendingStates[c.FeatureName] = &FeatureState{Fault: fs.Fault, FaultedMethods: fs.FaultedMethods}
+			} else {
+				delete(pendingStates, c.FeatureName)
+			}
+			continue
+		}
+		st.mu.Lock()
+		switch {
+		case c.FuncName == "":
+			st.fault = fs.Fault
+		case c.New == "active":
+			delete(st.faults, c.FuncName)
+		default:
+			if st.faults == nil {
+				st.faults = make(map[string]*methodFault)
+			}
+			st.faults[c.FuncName] = &methodFault{count: st.faultThreshold, fault: true}
+		}
+		st.mu.Unlock()
+	}
+	if len(changes) > 0 {
+		saveState()
+	}
+	for _, c := range changes {
+		if c.Kind == "group" {
+			auditGroup(a, AuditRestore, c.GroupName, c.Old, c.New)
+			continue
+		}
+		audit(a, AuditRestore, c.FeatureName, c.FuncName, c.Old, c.New)
+	}
+	return changes
+}
+
+// featureNames returns the names of features in the all states.
+func featureNames(states ...*State) map[string]bool {
+	names := make(map[string]bool)
+	for _, state := range states {
+		if state == nil {
+			continue
+		}
+		for name := range state.Features {
+			names[name] = true
+		}
+	}
+	return names
+}
+
+// groupNames returns the names of groups in the all states.
+func groupNames(states ...*State) map[string]bool {
+	names := make(map[string]bool)
+	for _, state := range states {
+		if state == nil {
+			continue
+		}
+		for name := range state.Groups {
+			names[name] = true
+		}
+	}
+	return names
+}
+
+// groupString returns the member feature names of group in state as string.
+// It returns empty if the group doesn't exist.
+func groupString(state *State, groupName string) string {
+	if state == nil {
+		return ""
+	}
+	return strings.Join(state.Groups[groupName], ",")
+}
+
+// featureState returns the state of feature in state. It returns an empty state if it doesn't exist.
+func featureState(state *State, featureName string) *FeatureState {
+	if state != nil && state.Features[featureName] != nil {
+		return state.Features[featureName]
+	}
+	return &FeatureState{}
+}
+
+// overrideString returns the forced activation and its reason as string.
+func overrideString(o *OverrideState) string {
+	if o == nil {
+		return ""
+	}
+	if o.Reason == "" {
+		return activation(*o, true)
+	}
+	return fmt.Sprintf("%s (%s)", activation(*o, true), o.Reason)
+}
+
+// faultString returns the fault state as string.
+func faultString(fault bool) string {
+	if fault {
+		return "fault"
+	}
+	return "active"
+}
@@ -0,0 +1,118 @@
+package gocchan
+
+import (
+	"encoding/json"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func Test_Snapshot(t *testing.T) {
+	defer Isolate()()
+	current := time.Date(2014, 6, 1, 0, 0, 0, 0, time.UTC)
+	SetClock(ClockFunc(func() time.Time { return current }))
+	AddFeature("a", &TestFeature{t, "a", true, nil, nil})
+	AddFeature("b", &TestFeature{t, "b", true, nil, nil})
+	OverrideWithReason("a", false, "incident")
+	AddGroup("g", "x", "y")
+	b, err := json.Marshal(Snapshot())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var snapshot State
+	if err := json.Unmarshal(b, &snapshot); err != nil {
+		t.Fatal(err)
+	}
+
+	Invoke("ctx", "b", "FuncPanic", nil)
+	Override("a", true)
+	Override("b", false)
+	featureStatus["a"].fault = true
+	AddGroup("g", "y")
+	AddGroup("h", "x")
+	expected := []Change{
+		{GroupName: "g", Kind: "group", Old: "y", New: "x,y"},
+		{GroupName: "h", Kind: "group", Old: "x", New: ""},
+		{FeatureName: "a", Kind: "override", Old: "active", New: "inactive (incident)"},
+		{FeatureName: "a", Kind: "fault", Old: "fault", New: "active"},
+		{FeatureName: "b", Kind: "override", Old: "inactive", New: ""},
+		{FeatureName: "b", FuncName: "FuncPanic", Kind: "fault", Old: "fault", New: "active"},
+	}
+	actual := Diff(Snapshot(), &snapshot)
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Expect %v, but %v", expected, actual)
+	}
+	actual = As("naoina", "rollback").Restore(&snapshot)
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Expect %v, but %v", expected, actual)
+	}
+	if actual := Diff(Snapshot(), &snapshot); len(actual) != 0 {
+		t.Errorf("Expect no changes, but %v", actual)
+	}
+	if ActiveIf("a", "ctx") || !ActiveIf("b", "ctx") || !IsMethodActive("b", "FuncPanic") || !reflect.DeepEqual(groups, map[string][]string{"g": {"x", "y"}}) {
+		t.Errorf("state hasn't been restored")
+	}
+	entries := AuditLog()
+	entry := entries[len(entries)-1]
+	if entry.Action != AuditRestore || entry.Actor != "naoina" || entry.FuncName != "FuncPanic" {
+		t.Errorf("restore hasn't been audited: %v", entry)
+	}
+}
+
+func Test_Restore_pending(t *testing.T) {
+	defer Isolate()()
+	snapshot := &State{Features: map[string]*FeatureState{
+		"later": {FaultedMethods: []string{"Func1"}},
+	}}
+	Restore(snapshot)
+	if actual := Diff(Snapshot(), snapshot); len(actual) != 0 {
+		t.Errorf("Expect no changes, but %v", actual)
+	}
+	AddFeature("later", &TestFeature{t, "later", true, nil, nil})
+	if IsMethodActive("later", "Func1") {
+		t.Errorf("fault state hasn't been restored")
+	}
+	Restore(&State{})
+	if !IsMethodActive("later", "Func1") {
+		t.Errorf("fault state hasn't been cleared")
+	}
+}
+
+func Test_Restore_concurrent(t *testing.T) {
+	defer Isolate()()
+	AddFeature("a", &TestFeature{t, "a", true, nil, nil})
+	AddGroup("g", "a")
+	faulted := &State{
+		Features: map[string]*FeatureState{"a": {Fault: true}},
+		Groups:   map[string][]string{"g": {"a", "b"}},
+	}
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			Restore(faulted)
+			Restore(&State{Groups: map[string][]string{"g": {"a"}}})
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		ActiveIf("a", "s")
+		IsActive("a")
+		Features()
+	}
+	wg.Wait()
+}
+
+func Test_Change_String(t *testing.T) {
+	for c, expected := range map[Change]string{
+		{FeatureName: "a", Kind: "override", Old: "", New: "active"}:                  "feature `a`: override \"\" -> \"active\"",
+		{FeatureName: "a", FuncName: "F", Kind: "fault", Old: "active", New: "fault"}: "feature `a` method `F`: fault \"active\" -> \"fault\"",
+		{GroupName: "g", Kind: "group", Old: "", New: "a,b"}:                          "group `g`: group \"\" -> \"a,b\"",
+	} {
+		actual := c.String()
+		if !reflect.DeepEqual(actual, expected) {
+			t.Errorf("Expect %q, but %q", expected, actual)
+		}
+	}
+}
@@ -0,0 +1,185 @@
+package gocchan
+
+import (
+	"encoding/json"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// State represents the persistent state of features.
+type State struct {
+	// state by feature name.
+	Features map[string]*FeatureState `json:"features,omitempty"`
+
+	// member feature names by group name.
+	// It is included only in the snapshot, and isn't persisted by the state store. See Snapshot.
+	Groups map[string][]string `json:"groups,omitempty"`
+}
+
+// FeatureState represents the persistent state of a feature.
+type FeatureState struct {
+	// Whether the feature was fault.
+	Fault bool `json:"fault,omitempty"`
+
+	// names of fault methods.
+	FaultedMethods []string `json:"faultedMethods,omitempty"`
+
+	// forced activation of the feature.
+	Override *OverrideState `json:"override,omitempty"`
+}
+
+// OverrideState represents a forced activation of feature.
+type OverrideState struct {
+	// forced activation.
+	Active bool `json:"active"`
+
+	// reason why the feature was forced.
+	Reason string `json:"reason,omitempty"`
+
+	// time when the feature was forced.
+	Time time.Time `json:"time"`
+}
+
+// StateStore is an interface of store of the persistent state of features.
+type StateStore interface {
+	// Load returns the stored state.
+	Load() (*State, error)
+
+	// Save stores the state.
+	Save(state *State) error
+}
+
+var (
+	// global state store.
+	stateStore StateStore
+
+	// loaded states of the features which haven't been added yet.
+	pendingStates = make(map[string]*FeatureState)
+)
+
+// SetStateStore sets the global state store, and restores the fault state and the forced activations from it.
+// The state of the features which haven't been added yet is restored when they're added by AddFeature.
+// After that, the state is saved to store whenever it has been changed.
+// The error of saving is logged by the standard logger.
+// Note that the restored fault methods aren't recovered by the half-open mechanism. See ResetFault.
+// If store is nil, the state isn't persisted.
+func SetStateStore(store StateStore) error {
+	stateStore = store
+	if store == nil {
+		return nil
+	}
+	state, err := store.Load()
+	if err != nil {
+		return err
+	}
+	for name, fs := range state.Features {
+		if fs.Override != nil {
+			old, exists := storeOverride(name, *fs.Override)
+			audit(restoredBy, AuditRestore, name, "", activation(old, exists), activation(*fs.Override, true))
+		}
+		if st := featureStatus[name]; st != nil {
+			st.restore(name, fs)
+		} else {
+			pendingStates[name] = fs
+		}
+	}
+	return nil
+}
+
+// restoredBy is the actor of the changes restored from the state store.
+var restoredBy = Actor{Name: SystemActor, Reason: "restored from state store"}
+
+// restore restores the fault state of the feature from fs.
+func (st *status) restore(featureName string, fs *FeatureState) {
+	st.mu.Lock()
+	st.fault = st.fault || fs.Fault
+	if len(fs.FaultedMethods) > 0 && st.faults == nil {
+		st.faults = make(map[string]*methodFault)
+	}
+	for _, name := range fs.FaultedMethods {
+		st.faults[name] = &methodFault{count: st.faultThreshold, fault: true}
+	}
+	st.mu.Unlock()
+	if fs.Fault {
+		audit(restoredBy, AuditRestore, featureName, "", "", "fault")
+	}
+	for _, name := range fs.FaultedMethods {
+		audit(restoredBy, AuditRestore, featureName, name, "", "fault")
+	}
+}
+
+// currentState returns the current state of features.
+func currentState() *State {
+	state := &State{Features: make(map[string]*FeatureState)}
+	for name, fs := range pendingStates {
+		state.Features[name] = &FeatureState{Fault: fs.Fault, FaultedMethods: fs.FaultedMethods}
+	}
+	for name, st := range featureStatus {
+		fault := st.isFault()
+		if methods := st.faultedMethods(); fault || len(methods) > 0 {
+			state.Features[name] = &FeatureState{Fault: fault, FaultedMethods: methods}
+		}
+	}
+	overridesMu.RLock()
+	for name, o := range overrides {
+		o := o
+		if state.Features[name] == nil {
+			state.Features[name] = &FeatureState{}
+		}
+		state.Features[name].Override = &o
+	}
+	overridesMu.RUnlock()
+	return state
+}
+
+// saveState saves the current state to the global state store.
+func saveState() {
+	if stateStore == nil {
+		return
+	}
+	if err := stateStore.Save(currentState()); err != nil {
+		log.Printf("gocchan: failed to save state: %v", err)
+	}
+}
+
+// FileStateStore is a StateStore which stores the state in a JSON file.
+type FileStateStore struct {
+	name string
+	mu   sync.Mutex
+}
+
+// NewFileStateStore returns a new FileStateStore which stores the state in the file named name.
+func NewFileStateStore(name string) *FileStateStore {
+	return &FileStateStore{name: name}
+}
+
+// Load returns the state in the file. If the file doesn't exist, it returns an empty state.
+func (s *FileStateStore) Load() (*State, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	state := &State{}
+	b, err := os.ReadFile(s.name)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return state, nil
+		}
+		return nil, err
+	}
+	if err := json.Unmarshal(b, state); err != nil {
+		return nil, err
+	}
+	return state, nil
+}
+
+// Save writes the state to the file.
+func (s *FileStateStore) Save(state *State) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	b, err := json.MarshalIndent(state, "", "\t")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(s.name, b)
+}
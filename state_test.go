@@ -0,0 +1,114 @@
+package gocchan
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func Test_StateStore(t *testing.T) {
+	defer Isolate()()
+	current := time.Date(2014, 6, 1, 0, 0, 0, 0, time.UTC)
+	SetClock(ClockFunc(func() time.Time { return current }))
+	name := filepath.Join(t.TempDir(), "state.json")
+	if err := SetStateStore(NewFileStateStore(name)); err != nil {
+		t.Fatal(err)
+	}
+	AddFeature("test", &TestFeature{t, "test", true, nil, nil})
+	Invoke("ctx", "test", "FuncPanic", nil)
+	OverrideWithReason("forced", false, "incident")
+	Override("test", true)
+	ClearOverride("test")
+
+	restore := Isolate()
+	SetClock(ClockFunc(func() time.Time { return current }))
+	if err := SetStateStore(NewFileStateStore(name)); err != nil {
+		t.Fatal(err)
+	}
+	if IsMethodActive("test", "FuncPanic") {
+		t.Errorf("fault state hasn't been restored before the feature is added")
+	}
+	AddFeature("test", &TestFeature{t, "test", true, nil, nil})
+	AddFeature("forced", &TestFeature{t, "forced", true, nil, nil})
+	var actual interface{} = []bool{IsMethodActive("test", "FuncPanic"), IsMethodActive("test", "Func1"), ActiveIf("forced", "ctx"), ActiveIf("test", "ctx")}
+	var expected interface{} = []bool{false, true, false, true}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Expect %v, but %v", expected, actual)
+	}
+	actual = Features()[0].Override
+	expected = &OverrideState{Active: false, Reason: "incident", Time: current}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Expect %v, but %v", expected, actual)
+	}
+
+	ResetFault("test")
+	state, err := NewFileStateStore(name).Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	actual = state
+	expected = &State{Features: map[string]*FeatureState{
+		"forced": {Override: &OverrideState{Active: false, Reason: "incident", Time: current}},
+	}}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Expect %v, but %v", expected, actual)
+	}
+	restore()
+}
+
+func Test_StateStore_pending(t *testing.T) {
+	defer Isolate()()
+	name := filepath.Join(t.TempDir(), "state.json")
+	store := NewFileStateStore(name)
+	if err := store.Save(&State{Features: map[string]*FeatureState{
+		"later": {Fault: true},
+	}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := SetStateStore(store); err != nil {
+		t.Fatal(err)
+	}
+	Override("other", true)
+	state, err := store.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var actual interface{} = state.Features["later"]
+	var expected interface{} = &FeatureState{Fault: true}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Expect %v, but %v", expected, actual)
+	}
+	AddFeature("later", &TestFeature{t, "later", true, nil, nil})
+	if IsActive("later") {
+		t.Errorf("fault state hasn't been restored")
+	}
+}
+
+func Test_FileStateStore(t *testing.T) {
+	dir := t.TempDir()
+	state, err := NewFileStateStore(filepath.Join(dir, "missing.json")).Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var actual interface{} = state
+	var expected interface{} = &State{}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Expect %v, but %v", expected, actual)
+	}
+	name := filepath.Join(dir, "invalid.json")
+	if err := os.WriteFile(name, []byte("{"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewFileStateStore(name).Load(); err == nil {
+		t.Errorf("no error returned by invalid file")
+	}
+	defer Isolate()()
+	if err := SetStateStore(NewFileStateStore(name)); err == nil {
+		t.Errorf("no error returned by invalid file")
+	}
+	if err := SetStateStore(nil); err != nil {
+		t.Error(err)
+	}
+}
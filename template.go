@@ -0,0 +1,83 @@
+package gocchan
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+)
+
+// FuncMap returns a template.FuncMap that provides the helpers for feature-gated rendering.
+// tmpl is used to look up the alternate templates, and it should be the template which the FuncMap is added to.
+//
+// The following functions are provided.
+//
+//	featureActive featureName context [options...]
+//		Returns the result of ActiveIf.
+//	featureRender featureName templateName context [options...]
+//		Renders the template named templateName with context if the feature is active.
+//		When featureName hasn't been added, templateName hasn't been defined, the feature isn't active,
+//		or any errors occurred, returns empty.
+//		Also if any errors occurred at least once, next rendering of the template will always return empty
+//		as well as the method of Invoke.
+//		The fault of template is recorded as the method named "template:" followed by templateName,
+//		so that it doesn't share the fault state with the method of the same name.
+//		It isn't recovered automatically since the latency isn't tracked for templates,
+//		and can be reset by ResetFault(featureName, "template:"+templateName).
+//
+// The default block can be written as an else block of the with action, like the defaultFunc of Invoke.
+//
+//	{{with featureRender "hello" "hello/gocchan" .}}{{.}}{{else}}Hello world!{{end}}
+func FuncMap(tmpl *template.Template) template.FuncMap {
+	return template.FuncMap{
+		"featureActive": ActiveIf,
+		"featureRender": func(featureName, templateName string, context interface{}, options ...interface{}) template.HTML {
+			return render(tmpl, featureName, templateName, context, options...)
+		},
+	}
+}
+
+// render renders the template named templateName with context if the feature associated with featureName is active.
+// It returns empty if the template can't be rendered.
+func render(tmpl *template.Template, featureName, templateName string, context interface{}, options ...interface{}) (html template.HTML) {
+	status := featureStatus[featureName]
+	funcName := templateFuncName(templateName)
+	defer func() {
+		if err := recover(); err != nil {
+			if err != ErrInvokeDefault {
+				status.recordFault(featureName, funcName, err)
+			}
+			notifyInvocation(EventDefaultInvoked, featureName, funcName)
+			html = ""
+		}
+	}()
+	if status == nil {
+		err := fmt.Errorf("feature has not been added: `%s`", featureName)
+		notify(EventFeatureHasNotBeenAdded, featureName, funcName, err)
+		panic(ErrInvokeDefault)
+	}
+	notifyIfExpired(status, featureName, funcName)
+	if status.methodFault(funcName) {
+		panic(ErrInvokeDefault)
+	}
+	t := tmpl.Lookup(templateName)
+	if t == nil {
+		err := fmt.Errorf("template is not found: `%s` for feature `%s`", templateName, featureName)
+		notify(EventFeatureMethodMissing, featureName, funcName, err)
+		panic(ErrInvokeDefault)
+	}
+	if !activeIf(status, featureName, context, options...) {
+		panic(ErrInvokeDefault)
+	}
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, context); err != nil {
+		panic(err)
+	}
+	status.recordSuccess(funcName)
+	notifyInvocation(EventFeatureInvoked, featureName, funcName)
+	return template.HTML(buf.String())
+}
+
+// templateFuncName returns the name of the template named templateName to record its fault state and events.
+func templateFuncName(templateName string) string {
+	return "template:" + templateName
+}
@@ -0,0 +1,98 @@
+package gocchan
+
+import (
+	"bytes"
+	"html/template"
+	"reflect"
+	"testing"
+)
+
+func newTestTemplate(t *testing.T, text string) *template.Template {
+	tmpl := template.New("root")
+	tmpl = template.Must(tmpl.Funcs(FuncMap(tmpl)).Parse(text))
+	template.Must(tmpl.New("alt").Parse(`<b>{{.}}</b>`))
+	template.Must(tmpl.New("broken").Parse(`{{.Unknown}}`))
+	return tmpl
+}
+
+func execTestTemplate(t *testing.T, tmpl *template.Template, context interface{}) string {
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, context); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
+
+func Test_FuncMap_featureActive(t *testing.T) {
+	defer Isolate()()
+	tmpl := newTestTemplate(t, `{{if featureActive "testfeature" . "opt1"}}active{{else}}inactive{{end}}`)
+	for _, v := range []struct {
+		added, active bool
+		expected      string
+	}{
+		{false, true, "inactive"},
+		{true, true, "active"},
+		{true, false, "inactive"},
+	} {
+		featureStatus = make(map[string]*status)
+		feature := &TestFeature{t, "test", v.active, nil, nil}
+		if v.added {
+			featureStatus["testfeature"] = &status{feature: feature}
+		}
+		actual := execTestTemplate(t, tmpl, "ctx")
+		expected := v.expected
+		if !reflect.DeepEqual(actual, expected) {
+			t.Errorf("added is %v, active is %v expect %q, but %q", v.added, v.active, expected, actual)
+		}
+	}
+}
+
+func Test_FuncMap_featureRender(t *testing.T) {
+	defer Isolate()()
+	init := func(name string, active bool) {
+		featureStatus["testfeature"] = &status{feature: &TestFeature{t, name, active, nil, nil}}
+	}
+	for _, v := range []struct {
+		text     string
+		active   bool
+		expected string
+	}{
+		{`{{with featureRender "testfeature" "alt" .}}{{.}}{{else}}default{{end}}`, true, "<b>&lt;ctx&gt;</b>"},
+		{`{{with featureRender "testfeature" "alt" .}}{{.}}{{else}}default{{end}}`, false, "default"},
+		{`{{with featureRender "unknown" "alt" .}}{{.}}{{else}}default{{end}}`, true, "default"},
+		{`{{with featureRender "testfeature" "unknown" .}}{{.}}{{else}}default{{end}}`, true, "default"},
+	} {
+		init("test", v.active)
+		tmpl := newTestTemplate(t, v.text)
+		actual := execTestTemplate(t, tmpl, "<ctx>")
+		expected := v.expected
+		if !reflect.DeepEqual(actual, expected) {
+			t.Errorf("%v: expect %q, but %q", v.text, expected, actual)
+		}
+	}
+
+	init("test", true)
+	tmpl := newTestTemplate(t, `{{with featureRender "testfeature" "broken" .}}{{.}}{{else}}default{{end}}`)
+	actual := execTestTemplate(t, tmpl, "ctx")
+	expected := "default"
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Expect %q, but %q", expected, actual)
+	}
+	if IsMethodActive("testfeature", "template:broken") {
+		t.Errorf("template hasn't been fault by error of template")
+	}
+	if !IsMethodActive("testfeature", "broken") {
+		t.Errorf("method of the same name has been fault by error of template")
+	}
+	ResetFault("testfeature", "template:broken")
+	if !IsMethodActive("testfeature", "template:broken") {
+		t.Errorf("fault of template hasn't been reset")
+	}
+
+	tmpl = newTestTemplate(t, `{{with featureRender "testfeature" "alt" .}}{{.}}{{else}}default{{end}}`)
+	actual = execTestTemplate(t, tmpl, "ctx")
+	expected = "<b>ctx</b>"
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Expect %q, but %q after fault of other template", expected, actual)
+	}
+}
@@ -0,0 +1,80 @@
+package gocchan
+
+import (
+	"fmt"
+	"reflect"
+	"time"
+)
+
+// Timeout returns an option that sets the timeout of execution of the methods of the feature.
+// When a method doesn't return within d, Invoke abandons it, notifies EventFeatureTimeout
+// and invokes the defaultFunc. The timeout is counted as a fault. See FaultThreshold.
+// Note that the abandoned method keeps running in the background until it returns, and its result is discarded.
+// If d isn't positive, it panic.
+func Timeout(d time.Duration) Option {
+	if d <= 0 {
+		panic(fmt.Sprintf("timeout must be positive: %v", d))
+	}
+	return func(st *status) {
+		st.timeout = d
+	}
+}
+
+// InvokeTimeout is like Invoke but uses timeout instead of the timeout of the feature.
+// If timeout isn't positive, the timeout of the feature is used.
+func InvokeTimeout(context interface{}, featureName, funcName string, timeout time.Duration, defaultFunc func(), options ...interface{}) {
+	invoke(context, featureName, funcName, nil, timeout, defaultFunc, options...)
+}
+
+// callResult represents the result of the method which is called in the background.
+type callResult struct {
+	out []reflect.Value
+
+	// recovered value if the method panicked.
+	err      interface{}
+	panicked bool
+}
+
+// call calls the method f with cvalue followed by avalues and returns its results.
+// If timeout is positive and the method doesn't return within timeout,
+// it records the timeout and panics with ErrInvokeDefault.
+// The panic in the method is propagated to the caller.
+// The execution in flight is released when the method returns. See MaxConcurrency.
+func call(status *status, featureName, funcName string, timeout time.Duration, f, cvalue reflect.Value, avalues []reflect.Value) []reflect.Value {
+	if timeout <= 0 {
+		defer status.release()
+		return f.Call(append([]reflect.Value{cvalue}, avalues...))
+	}
+	done := make(chan *callResult, 1)
+	go func() {
+		defer status.release()
+		panicked := true
+		defer func() {
+			if panicked {
+				done <- &callResult{err: recover(), panicked: true}
+			}
+		}()
+		out := f.Call(append([]reflect.Value{cvalue}, avalues...))
+		panicked = false
+		done <- &callResult{out: out}
+	}()
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case r := <-done:
+		if r.panicked {
+			panic(r.err)
+		}
+		return r.out
+	case <-timer.C:
+		status.recordTimeout(featureName, funcName, timeout)
+		panic(ErrInvokeDefault)
+	}
+}
+
+// recordTimeout records the timeout of the method funcName as a fault and notifies EventFeatureTimeout.
+func (st *status) recordTimeout(featureName, funcName string, timeout time.Duration) {
+	st.countFault(featureName, funcName)
+	err := fmt.Errorf("method has been timed out after %v: `%s` in feature `%s`", timeout, funcName, featureName)
+	notify(EventFeatureTimeout, featureName, funcName, err)
+}
@@ -0,0 +1,99 @@
+package gocchan
+
+import (
+	"reflect"
+	"sort"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type slowFeature struct {
+	release chan struct{}
+	panics  bool
+}
+
+func (f *slowFeature) ActiveIf(context interface{}, options ...interface{}) bool {
+	return true
+}
+
+func (f *slowFeature) Func(context interface{}) {
+	<-f.release
+	if f.panics {
+		panic("slow")
+	}
+}
+
+func Test_Timeout(t *testing.T) {
+	func() {
+		defer func() {
+			if err := recover(); err == nil {
+				t.Errorf("panic doesn't occurred by timeout of zero")
+			}
+		}()
+		Timeout(0)
+	}()
+
+	defer Isolate()()
+	events := make(chanListener, 10)
+	AddEventListener(events)
+	feature := &slowFeature{release: make(chan struct{}), panics: true}
+	AddFeature("test", feature, Timeout(10*time.Millisecond), FaultThreshold(2))
+	var called int32
+	Invoke("ctx", "test", "Func", func() {
+		atomic.AddInt32(&called, 1)
+	})
+	close(feature.release)
+	time.Sleep(10 * time.Millisecond)
+	if n := atomic.LoadInt32(&called); n != 1 {
+		t.Errorf("Expect defaultFunc to be called once, but %v", n)
+	}
+	if !IsMethodActive("test", "Func") {
+		t.Errorf("method has been fault before timeouts reach threshold")
+	}
+	WaitNotify()
+	close(events)
+	var actual []EventType
+	for event := range events {
+		actual = append(actual, event.Type)
+	}
+	sort.Slice(actual, func(i, j int) bool {
+		return actual[i] < actual[j]
+	})
+	expected := []EventType{EventFeatureTimeout}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Expect %v, but %v", expected, actual)
+	}
+}
+
+func Test_InvokeTimeout(t *testing.T) {
+	defer Isolate()()
+	feature := &slowFeature{release: make(chan struct{})}
+	AddFeature("test", feature)
+	defer close(feature.release)
+	called := false
+	InvokeTimeout("ctx", "test", "Func", 10*time.Millisecond, func() {
+		called = true
+	})
+	if !called {
+		t.Errorf("defaultFunc hasn't been called by timeout")
+	}
+	if IsMethodActive("test", "Func") {
+		t.Errorf("method hasn't been fault by timeout")
+	}
+
+	AddFeature("fast", &TestFeature{t, "fast", true, nil, nil}, Timeout(time.Hour))
+	InvokeTimeout("ctx", "fast", "Func1", 0, func() {
+		t.Errorf("defaultFunc has been called")
+	})
+	called = false
+	InvokeTimeout("ctx", "fast", "FuncPanic", time.Hour, func() {
+		called = true
+	})
+	if !called {
+		t.Errorf("defaultFunc hasn't been called by panic within timeout")
+	}
+	if IsMethodActive("fast", "FuncPanic") {
+		t.Errorf("method hasn't been fault by panic within timeout")
+	}
+}
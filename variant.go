@@ -0,0 +1,111 @@
+package gocchan
+
+import (
+	"fmt"
+	"hash/fnv"
+)
+
+// Subject is an interface of context which identifies the subject such as a user.
+// The subject is used to assign the variant of experiment deterministically.
+// A string context is also regarded as a subject.
+type Subject interface {
+	// SubjectKey returns the key of subject.
+	SubjectKey() string
+}
+
+// subjectKey returns the key of subject which is identified by context.
+func subjectKey(context interface{}) (string, bool) {
+	switch c := context.(type) {
+	case Subject:
+		return c.SubjectKey(), true
+	case string:
+		return c, true
+	}
+	return "", false
+}
+
+// Variant represents a variant of experiment.
+type Variant struct {
+	// name of variant.
+	// The method which is suffixed with the name is invoked for the subject who is assigned to the variant.
+	Name string
+
+	// weight of variant. It must be a positive number.
+	Weight int
+}
+
+// Variants returns an option that makes the feature an experiment with variants.
+// Invoke invokes the method named funcName suffixed with the name of the variant assigned to the subject.
+// e.g. Invoke(user, "button", "Render", nil) invokes RenderRed or RenderBlue with the following option.
+//
+//	Variants(Variant{"Red", 50}, Variant{"Blue", 50})
+//
+// The subject is assigned to the variant deterministically in proportion to the weight.
+// When the subject can't be identified by context, will invoke the defaultFunc.
+// If any weights aren't positive, it panic.
+func Variants(variants ...Variant) Option {
+	for _, v := range variants {
+		if v.Weight <= 0 {
+			panic(fmt.Sprintf("weight of variant `%s` must be positive: %d", v.Name, v.Weight))
+		}
+	}
+	return func(st *status) {
+		st.variants = append(st.variants, variants...)
+	}
+}
+
+// VariantOf returns the name of variant which the subject identified by context is assigned to.
+// If the feature isn't an experiment or the subject can't be identified, it returns false.
+func VariantOf(featureName string, context interface{}) (string, bool) {
+	status := featureStatus[featureName]
+	if status == nil {
+		return "", false
+	}
+	return assignVariant(status, featureName, context)
+}
+
+// assignVariant returns the name of variant which the subject identified by context is assigned to.
+// If the subject has been assigned to the variant in the assignment store, it is returned.
+func assignVariant(status *status, featureName string, context interface{}) (string, bool) {
+	if len(status.variants) == 0 {
+		return "", false
+	}
+	key, ok := subjectKey(context)
+	if !ok {
+		return "", false
+	}
+	if a, ok := loadAssignment(featureName, key); ok {
+		for _, v := range status.variants {
+			if v.Name == a.Variant {
+				return v.Name, true
+			}
+		}
+	}
+	return variantOf(status, featureName, key), true
+}
+
+// variantOf returns the name of variant which the subject is assigned to in proportion to the weight.
+func variantOf(status *status, featureName, key string) string {
+	total := 0
+	for _, v := range status.variants {
+		total += v.Weight
+	}
+	n := int(hash(featureName, key) % uint32(total))
+	for _, v := range status.variants {
+		if n < v.Weight {
+			return v.Name
+		}
+		n -= v.Weight
+	}
+	panic("BUG: unreachable")
+}
+
+// hash returns the hash value of keys.
+func hash(keys ...string) uint32 {
+	h := fnv.New32a()
+	for _, key := range keys {
+		h.Write([]byte(key))
+		h.Write([]byte{0})
+	}
+	return h.Sum32()
+}
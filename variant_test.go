@@ -0,0 +1,119 @@
+package gocchan
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+type testSubject string
+
+func (s testSubject) SubjectKey() string {
+	return string(s)
+}
+
+type TestExperiment struct {
+	TestFeature
+}
+
+func (f *TestExperiment) FuncA(context interface{}) {
+	f.calledBy = append(f.calledBy, fmt.Sprintf("FuncA:%v", context))
+}
+
+func (f *TestExperiment) FuncB(context interface{}) {
+	f.calledBy = append(f.calledBy, fmt.Sprintf("FuncB:%v", context))
+}
+
+func (f *TestExperiment) FuncCPanic(context interface{}) {
+	panic("expected panic")
+}
+
+func Test_Variants(t *testing.T) {
+	func() {
+		defer func() {
+			if err := recover(); err == nil {
+				t.Errorf("panic doesn't occurred by non-positive weight")
+			}
+		}()
+		Variants(Variant{"A", 1}, Variant{"B", 0})
+	}()
+}
+
+func Test_VariantOf(t *testing.T) {
+	defer Isolate()()
+	AddFeature("test", &TestFeature{t, "test", true, nil, nil})
+	AddFeature("experiment", &TestExperiment{TestFeature{t, "test", true, nil, nil}}, Variants(Variant{"A", 1}, Variant{"B", 3}))
+	for _, v := range []struct {
+		featureName string
+		context     interface{}
+		expected    bool
+	}{
+		{"unknown", "subject", false},
+		{"test", "subject", false},
+		{"experiment", 1, false},
+		{"experiment", nil, false},
+		{"experiment", "subject", true},
+		{"experiment", testSubject("subject"), true},
+	} {
+		_, actual := VariantOf(v.featureName, v.context)
+		expected := v.expected
+		if !reflect.DeepEqual(actual, expected) {
+			t.Errorf("VariantOf(%q, %v) expect %v, but %v", v.featureName, v.context, expected, actual)
+		}
+	}
+
+	counts := make(map[string]int)
+	for i := 0; i < 1000; i++ {
+		subject := fmt.Sprintf("subject%d", i)
+		variant, _ := VariantOf("experiment", subject)
+		for j := 0; j < 3; j++ {
+			if v, _ := VariantOf("experiment", testSubject(subject)); v != variant {
+				t.Fatalf("variant of %v isn't deterministic: %v and %v", subject, variant, v)
+			}
+		}
+		counts[variant]++
+	}
+	if len(counts) != 2 || counts["A"] < 150 || counts["A"] > 350 {
+		t.Errorf("variants aren't assigned in proportion to weight: %v", counts)
+	}
+}
+
+func Test_Invoke_variants(t *testing.T) {
+	defer Isolate()()
+	feature := &TestExperiment{TestFeature{t, "test", true, nil, nil}}
+	AddFeature("experiment", feature, Variants(Variant{"A", 1}, Variant{"B", 1}))
+	var expected []string
+	for i := 0; i < 10; i++ {
+		subject := fmt.Sprintf("subject%d", i)
+		variant, _ := VariantOf("experiment", subject)
+		Invoke(subject, "experiment", "Func", func() {
+			t.Errorf("defaultFunc has been called")
+		})
+		expected = append(expected, fmt.Sprintf("Func%s:%s", variant, subject))
+	}
+	actual := feature.calledBy
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Expect %q, but %q", expected, actual)
+	}
+
+	called := false
+	Invoke(1, "experiment", "Func", func() {
+		called = true
+	})
+	if !called {
+		t.Errorf("defaultFunc hasn't been called by unidentified subject")
+	}
+
+	feature = &TestExperiment{TestFeature{t, "test", true, nil, nil}}
+	AddFeature("experiment", feature, Variants(Variant{"CPanic", 1}))
+	called = false
+	Invoke("subject", "experiment", "Func", func() {
+		called = true
+	})
+	if !called {
+		t.Errorf("defaultFunc hasn't been called by panic of variant")
+	}
+	if IsMethodActive("experiment", "FuncCPanic") {
+		t.Errorf("variant hasn't been fault by panic")
+	}
+}